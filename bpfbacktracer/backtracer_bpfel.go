@@ -0,0 +1,148 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64 || amd64p32 || arm || arm64 || mips64le || mips64p32le || mipsle || ppc64le || riscv64
+// +build 386 amd64 amd64p32 arm arm64 mips64le mips64p32le mipsle ppc64le riscv64
+
+package bpfbacktracer
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type backtracerKey struct {
+	Tid     uint32
+	Pad     uint32
+	KtimeNs uint64
+}
+
+type backtracerSample struct {
+	UserFrames   uint32
+	KernelFrames uint32
+	Ip           uint64
+	Sp           uint64
+	Fp           uint64
+	SiCode       int32
+	Pad          uint32
+	FaultAddr    uint64
+	UserStack    [127]uint64
+	KernelStack  [127]uint64
+}
+
+// loadBacktracer returns the embedded CollectionSpec for backtracer.
+func loadBacktracer() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BacktracerBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load backtracer: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBacktracerObjects loads backtracer and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*backtracerObjects
+//	*backtracerPrograms
+//	*backtracerMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBacktracerObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBacktracer()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// backtracerSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type backtracerSpecs struct {
+	backtracerProgramSpecs
+	backtracerMapSpecs
+}
+
+// backtracerSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type backtracerProgramSpecs struct {
+	GcoredumperCoreFentry  *ebpf.ProgramSpec `ebpf:"gcoredumper_core_fentry"`
+	GcoredumperCoreHandler *ebpf.ProgramSpec `ebpf:"gcoredumper_core_handler"`
+}
+
+// backtracerMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type backtracerMapSpecs struct {
+	GcoredEvents  *ebpf.MapSpec `ebpf:"gcored_events"`
+	GcoredSamples *ebpf.MapSpec `ebpf:"gcored_samples"`
+	GcoredScratch *ebpf.MapSpec `ebpf:"gcored_scratch"`
+}
+
+// backtracerObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBacktracerObjects or ebpf.CollectionSpec.LoadAndAssign.
+type backtracerObjects struct {
+	backtracerPrograms
+	backtracerMaps
+}
+
+func (o *backtracerObjects) Close() error {
+	return _BacktracerClose(
+		&o.backtracerPrograms,
+		&o.backtracerMaps,
+	)
+}
+
+// backtracerMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBacktracerObjects or ebpf.CollectionSpec.LoadAndAssign.
+type backtracerMaps struct {
+	GcoredEvents  *ebpf.Map `ebpf:"gcored_events"`
+	GcoredSamples *ebpf.Map `ebpf:"gcored_samples"`
+	GcoredScratch *ebpf.Map `ebpf:"gcored_scratch"`
+}
+
+func (m *backtracerMaps) Close() error {
+	return _BacktracerClose(
+		m.GcoredEvents,
+		m.GcoredSamples,
+		m.GcoredScratch,
+	)
+}
+
+// backtracerPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBacktracerObjects or ebpf.CollectionSpec.LoadAndAssign.
+type backtracerPrograms struct {
+	GcoredumperCoreFentry  *ebpf.Program `ebpf:"gcoredumper_core_fentry"`
+	GcoredumperCoreHandler *ebpf.Program `ebpf:"gcoredumper_core_handler"`
+}
+
+func (p *backtracerPrograms) Close() error {
+	return _BacktracerClose(
+		p.GcoredumperCoreFentry,
+		p.GcoredumperCoreHandler,
+	)
+}
+
+func _BacktracerClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed backtracer_bpfel.o
+var _BacktracerBytes []byte
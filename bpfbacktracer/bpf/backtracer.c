@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: GPL-2.0
+// Captures the user and kernel stacks of a crashing thread at do_coredump,
+// before the pipe handler is started, and keeps them in gcored_samples.
+
+#include "common.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+
+char __license[] SEC("license") = "GPL";
+
+#define MAX_FRAMES_NUMBER 127
+
+// TIDs are reused, the capture time tells samples of the same TID apart.
+struct key {
+	__u32 tid;
+	__u32 pad;
+	// CLOCK_BOOTTIME
+	__u64 ktime_ns;
+};
+
+struct sample {
+	// number of valid frames in user_stack and kernel_stack
+	__u32 user_frames;
+	__u32 kernel_frames;
+	// user space registers of the crashing thread
+	__u64 ip;
+	__u64 sp;
+	__u64 fp;
+	// from kernel_siginfo, fault_addr is meaningful for
+	// SIGSEGV, SIGBUS, SIGILL, SIGFPE and SIGTRAP only
+	__s32 si_code;
+	__u32 pad;
+	__u64 fault_addr;
+	__u64 user_stack[MAX_FRAMES_NUMBER];
+	__u64 kernel_stack[MAX_FRAMES_NUMBER];
+};
+
+// Rewritten by the loader, see BPFConfig.stack_depth.
+const volatile __u32 frames_number = MAX_FRAMES_NUMBER;
+
+// Consumers delete samples they read, LRU evicts the rest
+// instead of dropping new crashes once the map is full.
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, 128);
+	__type(key, struct key);
+	__type(value, struct sample);
+} gcored_samples SEC(".maps");
+
+#define TASK_COMM_LEN 16
+
+struct event {
+	// CLOCK_BOOTTIME
+	__u64 ktime_ns;
+	__u32 pid;
+	__u32 tid;
+	__u64 cgroup_id;
+	__s32 signal;
+	char comm[TASK_COMM_LEN];
+	__u32 pad;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_RINGBUF);
+	__uint(max_entries, 256 * 1024);
+} gcored_events SEC(".maps");
+
+// sample does not fit into the 512 bytes of BPF stack
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct sample);
+} gcored_scratch SEC(".maps");
+
+// read_regs stores ip, sp and fp of the current task.
+static __always_inline void read_regs(struct sample *sample)
+{
+	struct pt_regs *regs = bpf_task_pt_regs(bpf_get_current_task_btf());
+
+	if (bpf_core_field_exists(((struct pt_regs___x86 *)regs)->ip)) {
+		struct pt_regs___x86 *r = (void *)regs;
+
+		bpf_core_read(&sample->ip, sizeof(sample->ip), &r->ip);
+		bpf_core_read(&sample->sp, sizeof(sample->sp), &r->sp);
+		bpf_core_read(&sample->fp, sizeof(sample->fp), &r->bp);
+	} else if (bpf_core_field_exists(((struct pt_regs___arm64 *)regs)->pc)) {
+		struct pt_regs___arm64 *r = (void *)regs;
+
+		bpf_core_read(&sample->ip, sizeof(sample->ip), &r->pc);
+		bpf_core_read(&sample->sp, sizeof(sample->sp), &r->sp);
+		bpf_core_read(&sample->fp, sizeof(sample->fp), &r->regs[29]);
+	}
+}
+
+// coredump_siginfo returns the argument of
+// do_coredump(const kernel_siginfo_t *siginfo), vfs_coredump of newer kernels
+static __always_inline struct kernel_siginfo *coredump_siginfo(struct pt_regs *ctx)
+{
+	struct kernel_siginfo *siginfo = 0;
+
+	// relocated ctx offsets can not be loaded directly
+	if (bpf_core_field_exists(((struct pt_regs___x86 *)ctx)->di))
+		bpf_core_read(&siginfo, sizeof(siginfo), &((struct pt_regs___x86 *)ctx)->di);
+	else if (bpf_core_field_exists(((struct pt_regs___arm64 *)ctx)->regs))
+		bpf_core_read(&siginfo, sizeof(siginfo), &((struct pt_regs___arm64 *)ctx)->regs[0]);
+	return siginfo;
+}
+
+// read_siginfo stores si_code and the fault address.
+static __always_inline void read_siginfo(struct kernel_siginfo *siginfo, struct sample *sample)
+{
+	if (!siginfo)
+		return;
+
+	bpf_core_read(&sample->si_code, sizeof(sample->si_code), &siginfo->si_code);
+	bpf_core_read(&sample->fault_addr, sizeof(sample->fault_addr), &siginfo->_sifields._sigfault._addr);
+}
+
+// send_event notifies gcoredumperbpf about the crash.
+static __always_inline void send_event(struct kernel_siginfo *siginfo, struct key *key)
+{
+	struct event *event;
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+
+	event = bpf_ringbuf_reserve(&gcored_events, sizeof(*event), 0);
+	if (!event)
+		return;
+
+	event->ktime_ns = key->ktime_ns;
+	event->pid = pid_tgid >> 32;
+	event->tid = pid_tgid;
+	event->cgroup_id = bpf_get_current_cgroup_id();
+	event->signal = 0;
+	if (siginfo)
+		bpf_core_read(&event->signal, sizeof(event->signal), &siginfo->si_signo);
+	bpf_get_current_comm(event->comm, sizeof(event->comm));
+
+	bpf_ringbuf_submit(event, 0);
+}
+
+// handle_coredump is shared by the fentry program and the kprobe fallback,
+// ctx is accepted by bpf_get_stack in both.
+static __always_inline int handle_coredump(void *ctx, struct kernel_siginfo *siginfo)
+{
+	struct key key = {
+		.ktime_ns = bpf_ktime_get_boot_ns(),
+		// the lower half is TID
+		.tid = bpf_get_current_pid_tgid(),
+	};
+	__u32 zero = 0;
+	struct sample *sample;
+	__u32 size;
+	long n;
+
+	// every crash is reported, whether it is dumped or not
+	send_event(siginfo, &key);
+
+	sample = bpf_map_lookup_elem(&gcored_scratch, &zero);
+	if (!sample)
+		return 0;
+
+	// the mask keeps the verifier convinced that size is bounded,
+	// MAX_FRAMES_NUMBER is 2^7-1
+	size = (frames_number & MAX_FRAMES_NUMBER) * sizeof(__u64);
+
+	// registers, siginfo and the kernel stack are kept
+	// even if the user stack can not be read
+	n = bpf_get_stack(ctx, sample->user_stack, size, BPF_F_USER_STACK);
+	sample->user_frames = n > 0 ? n / sizeof(__u64) : 0;
+
+	n = bpf_get_stack(ctx, sample->kernel_stack, size, 0);
+	sample->kernel_frames = n > 0 ? n / sizeof(__u64) : 0;
+
+	// the scratch buffer keeps values of the previous crash
+	sample->ip = sample->sp = sample->fp = 0;
+	sample->si_code = 0;
+	sample->fault_addr = 0;
+	read_regs(sample);
+	read_siginfo(siginfo, sample);
+
+	bpf_map_update_elem(&gcored_samples, &key, sample, BPF_ANY);
+	return 0;
+}
+
+// fentry is attached with a bpf_link, which is pinned to survive restarts
+// of gcoredumperbpf. The target is resolved at load time, see TargetSymbols.
+SEC("fentry/do_coredump")
+int gcoredumper_core_fentry(__u64 *ctx)
+{
+	return handle_coredump(ctx, (struct kernel_siginfo *)ctx[0]);
+}
+
+// kprobe is used if BPF trampolines are not supported.
+SEC("kprobe/do_coredump")
+int gcoredumper_core_handler(struct pt_regs *ctx)
+{
+	return handle_coredump(ctx, coredump_siginfo(ctx));
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: GPL-2.0
+// Subset of libbpf bpf_core_read.h.
+#ifndef __GCOREDUMPER_BPF_CORE_READ_H__
+#define __GCOREDUMPER_BPF_CORE_READ_H__
+
+enum bpf_field_info_kind {
+	BPF_FIELD_BYTE_OFFSET = 0,
+	BPF_FIELD_BYTE_SIZE = 1,
+	BPF_FIELD_EXISTS = 2,
+};
+
+// bpf_core_field_exists is 1 if the field exists in the running kernel
+#define bpf_core_field_exists(field) \
+	__builtin_preserve_field_info(field, BPF_FIELD_EXISTS)
+
+// bpf_core_read reads a field of a kernel struct
+// at the offset relocated against the running kernel
+#define bpf_core_read(dst, sz, src) \
+	bpf_probe_read_kernel(dst, sz, (const void *)__builtin_preserve_access_index(src))
+
+#endif /* __GCOREDUMPER_BPF_CORE_READ_H__ */
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-2.0
+// Minimal subset of kernel and UAPI definitions the BPF program needs.
+// Structs read from kernel memory are marked with preserve_access_index,
+// so field offsets are relocated against the running kernel BTF (CO-RE).
+#ifndef __GCOREDUMPER_COMMON_H__
+#define __GCOREDUMPER_COMMON_H__
+
+typedef unsigned char __u8;
+typedef short int __s16;
+typedef short unsigned int __u16;
+typedef int __s32;
+typedef unsigned int __u32;
+typedef long long int __s64;
+typedef long long unsigned int __u64;
+
+enum bpf_map_type {
+	BPF_MAP_TYPE_HASH = 1,
+	BPF_MAP_TYPE_PERCPU_ARRAY = 6,
+	BPF_MAP_TYPE_LRU_HASH = 9,
+	BPF_MAP_TYPE_RINGBUF = 27,
+};
+
+enum {
+	BPF_ANY = 0,
+	BPF_NOEXIST = 1,
+	BPF_EXIST = 2,
+};
+
+enum {
+	BPF_F_USER_STACK = (1ULL << 8),
+};
+
+struct task_struct;
+
+// kprobe context and saved user space registers of a task.
+// The ___<arch> suffix is a CO-RE flavor: it is dropped when matching
+// against kernel types, and accesses to fields missing in the running
+// kernel are guarded by bpf_core_field_exists.
+struct pt_regs;
+
+struct pt_regs___x86 {
+	unsigned long bp;
+	unsigned long di;
+	unsigned long ip;
+	unsigned long sp;
+} __attribute__((preserve_access_index));
+
+struct pt_regs___arm64 {
+	__u64 regs[31];
+	__u64 sp;
+	__u64 pc;
+} __attribute__((preserve_access_index));
+
+union __sifields {
+	struct {
+		void *_addr;
+	} _sigfault;
+} __attribute__((preserve_access_index));
+
+struct kernel_siginfo {
+	int si_signo;
+	int si_code;
+	union __sifields _sifields;
+} __attribute__((preserve_access_index));
+
+#endif /* __GCOREDUMPER_COMMON_H__ */
@@ -0,0 +1,84 @@
+package bpfbacktracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// Event is sent by the BPF program on every do_coredump,
+// including processes which are not dumpable.
+type Event struct {
+	Pid      uint32    `json:"pid"`
+	Tid      uint32    `json:"tid"`
+	Comm     string    `json:"comm"`
+	CgroupID uint64    `json:"cgroup_id"`
+	Signal   int32     `json:"signal"`
+	Time     time.Time `json:"timestamp"`
+}
+
+// event is struct event from bpf/backtracer.c
+type event struct {
+	KtimeNs  uint64
+	Pid      uint32
+	Tid      uint32
+	CgroupID uint64
+	Signal   int32
+	Comm     [16]byte
+	_        uint32
+}
+
+// EventReader reads crash events from the ring buffer.
+type EventReader struct {
+	rd *ringbuf.Reader
+}
+
+// Events opens a reader of crash events. There must be a single reader.
+func (b *BpfBacktracer) Events() (*EventReader, error) {
+	rd, err := ringbuf.NewReader(b.maps.GcoredEvents)
+	if err != nil {
+		return nil, err
+	}
+	return &EventReader{rd: rd}, nil
+}
+
+// Read blocks until the next event. It returns ringbuf.ErrClosed
+// once the reader is closed.
+func (r *EventReader) Read() (*Event, error) {
+	record, err := r.rd.Read()
+	if err != nil {
+		return nil, err
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+	return parseEvent(record.RawSample, boot)
+}
+
+// Close interrupts pending Read calls.
+func (r *EventReader) Close() error {
+	return r.rd.Close()
+}
+
+func parseEvent(raw []byte, boot time.Time) (*Event, error) {
+	var e event
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &e); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	comm := e.Comm[:]
+	if i := bytes.IndexByte(comm, 0); i >= 0 {
+		comm = comm[:i]
+	}
+	return &Event{
+		Pid:      e.Pid,
+		Tid:      e.Tid,
+		Comm:     string(comm),
+		CgroupID: e.CgroupID,
+		Signal:   e.Signal,
+		Time:     boot.Add(time.Duration(e.KtimeNs)),
+	}, nil
+}
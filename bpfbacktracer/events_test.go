@@ -0,0 +1,109 @@
+package bpfbacktracer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseEvent(t *testing.T) {
+	e := event{
+		KtimeNs:  uint64(5 * time.Second),
+		Pid:      100,
+		Tid:      101,
+		CgroupID: 42,
+		Signal:   11,
+	}
+	copy(e.Comm[:], "crasher")
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &e); err != nil {
+		t.Fatal(err)
+	}
+	boot := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := parseEvent(buf.Bytes(), boot)
+	if err != nil {
+		t.Fatalf("parseEvent() = %v", err)
+	}
+	want := &Event{
+		Pid:      100,
+		Tid:      101,
+		Comm:     "crasher",
+		CgroupID: 42,
+		Signal:   11,
+		Time:     boot.Add(5 * time.Second),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseEvent() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseEvent(buf.Bytes()[:10], boot); err == nil {
+		t.Error("parseEvent() of a short sample = nil, want error")
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := NewEventStream(ln)
+	done := make(chan error)
+	go func() { done <- stream.Serve() }()
+
+	var clients []*bufio.Reader
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		clients = append(clients, bufio.NewReader(conn))
+	}
+	// wait for Serve to register both clients
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		stream.mu.Lock()
+		n := len(stream.clients)
+		stream.mu.Unlock()
+		if n == len(clients) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients connected, want %d", n, len(clients))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	want := &Event{Pid: 100, Tid: 101, Comm: "crasher", Signal: 11, Time: time.Unix(1650000000, 0).UTC()}
+	if err := stream.Publish(want); err != nil {
+		t.Fatalf("Publish() = %v", err)
+	}
+	for i, client := range clients {
+		line, err := client.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("client %d: %v", i, err)
+		}
+		var got Event
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("client %d: invalid JSON %q: %v", i, line, err)
+		}
+		if diff := cmp.Diff(want, &got); diff != "" {
+			t.Errorf("client %d: event mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
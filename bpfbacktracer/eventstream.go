@@ -0,0 +1,108 @@
+package bpfbacktracer
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+)
+
+// clientBacklog is the number of events buffered for a slow client.
+// Newer events are dropped for the client once it is full.
+const clientBacklog = 64
+
+// EventStream writes every published event as a line of JSON
+// to all connected clients.
+type EventStream struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	clients map[net.Conn]chan []byte
+	closed  bool
+}
+
+func NewEventStream(ln net.Listener) *EventStream {
+	return &EventStream{
+		ln:      ln,
+		clients: make(map[net.Conn]chan []byte),
+	}
+}
+
+// Serve accepts clients until Close is called.
+func (s *EventStream) Serve() error {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		ch := make(chan []byte, clientBacklog)
+		s.clients[conn] = ch
+		s.mu.Unlock()
+		go s.serveClient(conn, ch)
+	}
+}
+
+func (s *EventStream) serveClient(conn net.Conn, ch chan []byte) {
+	defer s.remove(conn)
+	for line := range ch {
+		if _, err := conn.Write(line); err != nil {
+			return
+		}
+	}
+}
+
+func (s *EventStream) remove(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch, ok := s.clients[conn]; ok {
+		delete(s.clients, conn)
+		close(ch)
+	}
+	conn.Close()
+}
+
+// Publish sends e to all connected clients without blocking.
+func (s *EventStream) Publish(e *Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, ch := range s.clients {
+		select {
+		case ch <- line:
+		default:
+			log.Printf("event stream client %v is too slow, event dropped", conn.RemoteAddr())
+		}
+	}
+	return nil
+}
+
+// Close stops Serve and disconnects all clients.
+func (s *EventStream) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]net.Conn, 0, len(s.clients))
+	for conn := range s.clients {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+	for _, conn := range conns {
+		s.remove(conn)
+	}
+	return err
+}
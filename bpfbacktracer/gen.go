@@ -0,0 +1,2 @@
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -Wall -Werror" -target bpfel backtracer bpf/backtracer.c -- -I./bpf/headers
+package bpfbacktracer
@@ -4,182 +4,284 @@ import (
 	"bytes"
 	"encoding"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cilium/ebpf"
-	"github.com/cilium/ebpf/asm"
 	"github.com/cilium/ebpf/link"
-	"golang.org/x/sys/unix"
+
+	"github.com/noxiouz/gcoredumper/configuration"
 )
 
 const (
+	// GCoreSamlesMapName and MaxFramesNumber must match bpf/backtracer.c
 	GCoreSamlesMapName = "gcored_samples"
-	FramesNumber       = 4
+	MaxFramesNumber    = 127
+	// LinkPinName is the pinned fentry link keeping the program attached
+	// while gcoredumperbpf restarts.
+	LinkPinName = "gcored_link"
+	// DefaultPinPath is a bpffs directory used if BPFConfig.pin_path is not set.
+	DefaultPinPath = "/sys/fs/bpf"
 )
 
-var gCoreSamlesMapSpec = &ebpf.MapSpec{
-	Name:       GCoreSamlesMapName,
-	Type:       ebpf.Hash,
-	KeySize:    4,                // u32 PID
-	ValueSize:  FramesNumber * 8, // 4 frames
-	MaxEntries: 128,
+// TargetSymbols are kernel functions the program can be attached to,
+// do_coredump is named vfs_coredump by newer kernels. The first one
+// the running kernel has is used.
+var TargetSymbols = []string{"vfs_coredump", "do_coredump"}
+
+// PinPath returns the bpffs directory of an instance.
+func PinPath(config *configuration.Config_BPFConfig) string {
+	if path := config.GetPinPath(); path != "" {
+		return path
+	}
+	return DefaultPinPath
+}
+
+// stackDepth returns the number of frames to capture.
+func stackDepth(config *configuration.Config_BPFConfig) uint32 {
+	depth := config.GetStackDepth()
+	if depth == 0 || depth > MaxFramesNumber {
+		return MaxFramesNumber
+	}
+	return depth
 }
 
+// BpfBacktracer keeps the program from bpf/backtracer.c loaded
+// and attached to one of TargetSymbols.
 type BpfBacktracer struct {
-	samplesMap *ebpf.Map
-	prog       *ebpf.Program
-	link       link.Link
-}
-
-/*
-	TODO: use bpf2go to generate a program
-	BCC version of a program:
-prog.c
-```
-#include <uapi/linux/ptrace.h>
-#include <linux/sched.h>
-
-struct data_t {
-    u64 stacks[4];
-};
-
-BPF_HASH(gcored_samples, u32, struct data_t, 128);
-
-void trace_stack(struct pt_regs *ctx) {
-    u32 pid = bpf_get_current_pid_tgid();
-    struct data_t data = {};
-    u64 ret = bpf_get_stack(ctx, data.stacks, sizeof(data.stacks), BPF_F_USER_STACK);
-    if (ret > 0) {
-        gcored_samples.update(&pid, &data);
-    }
-}
-```
-*/
+	maps    backtracerMaps
+	prog    *ebpf.Program
+	link    link.Link
+	pinPath string
+	target  string
+	// replacedMap is set once a stale pinned map is replaced
+	replacedMap bool
+}
+
+// fentryObjects and kprobeObjects load either of the programs.
+type fentryObjects struct {
+	backtracerMaps
+	Prog *ebpf.Program `ebpf:"gcoredumper_core_fentry"`
+}
+
+type kprobeObjects struct {
+	backtracerMaps
+	Prog *ebpf.Program `ebpf:"gcoredumper_core_handler"`
+}
+
 // NewBPFBacktracer creates a BPF Backtracer struct
-// that allocates map, prog and attaches kprobe.
-func NewBPFBacktracer() (*BpfBacktracer, error) {
-	samplesMap, err := ebpf.NewMap(gCoreSamlesMapSpec)
+// that allocates map, prog and attaches it to one of TargetSymbols.
+// The samples map and the link are pinned to PinPath. A map left by
+// a previous instance is reused, or replaced if its layout is stale.
+// A pinned link of a previous instance is detached only once
+// the new program is attached, so no crash is missed.
+// config may be nil.
+func NewBPFBacktracer(config *configuration.Config_BPFConfig) (*BpfBacktracer, error) {
+	spec, err := loadBacktracer()
 	if err != nil {
 		return nil, err
 	}
-	// TODO: configurable
-	samplesMap.Pin(filepath.Join("/sys/fs/bpf", GCoreSamlesMapName))
-	samplesMap.Freeze()
-	var progSpec = &ebpf.ProgramSpec{
-		Name:    "gcoredumper_core_handler",
-		Type:    ebpf.Kprobe,
-		License: "GPL",
-		Instructions: asm.Instructions{
-			// r6 = r1
-			asm.Mov.Reg(asm.R6, asm.R1),
-			// call bpf_get_current_pid_tgid#14
-			asm.FnGetCurrentPidTgid.Call(),
-			// *(u32*)(r10 -4) = r0
-			asm.StoreMem(asm.R10, -4, asm.R0, asm.Word),
-			// r1 = 0
-			asm.Mov.Imm(asm.R1, 0),
-			// allocate sample struct
-			// *(u64*)(r10 -16) = r1
-			// *(u64*)(r10 -24) = r1
-			// *(u64*)(r10 -32) = r1
-			// *(u64*)(r10 -40) = r1
-			asm.StoreMem(asm.RFP, -16, asm.R0, asm.DWord),
-			asm.StoreMem(asm.RFP, -24, asm.R0, asm.DWord),
-			asm.StoreMem(asm.RFP, -32, asm.R0, asm.DWord),
-			asm.StoreMem(asm.RFP, -40, asm.R0, asm.DWord),
-			// bpf_get_stack args:
-			// buf*
-			// r2 = r10
-			asm.Mov.Reg(asm.R2, asm.R10),
-			// r2 += -40
-			asm.Add.Imm(asm.R2, -40),
-			// ctx*
-			// r1 = r6
-			asm.Mov.Reg(asm.R1, asm.R6),
-			// size
-			// r3 = 32
-			asm.Mov.Imm(asm.R3, 32),
-			// flags
-			// r4 = 256
-			asm.Mov.Imm(asm.R4, unix.BPF_F_USER_STACK),
-			// call bpf_get_stack#67
-			asm.FnGetStack.Call(),
-			// r0 <<= 32
-			asm.LSh.Imm(asm.R0, 32),
-			// r0 >>= 32
-			asm.RSh.Imm(asm.R0, 32),
-			// if r0 == 0 goto exit
-			asm.JEq.Imm(asm.R0, 0, "exit"),
-			// bpf_map_update_elem args
-			// map
-			// r1 = <map at fd>
-			asm.LoadMapPtr(asm.R1, samplesMap.FD()),
-			// key
-			// r2 = r10
-			asm.Mov.Reg(asm.R2, asm.R10),
-			// r2 += -4
-			asm.Add.Imm(asm.R2, -4),
-			// value
-			// r3 = r10
-			asm.Mov.Reg(asm.R3, asm.R10),
-			// r3 += -40
-			asm.Add.Imm(asm.R3, -40),
-			// flag
-			// r4 = 0
-			asm.Mov.Imm(asm.R4, unix.BPF_ANY),
-			// call bpf_map_update_elem#2
-			asm.FnMapUpdateElem.Call(),
-			asm.Mov.Imm(asm.R0, 0).Sym("exit"),
-			// exit
-			asm.Return(),
-		},
-	}
-	prog, err := ebpf.NewProgram(progSpec)
+	if err := spec.RewriteConstants(map[string]interface{}{
+		"frames_number": stackDepth(config),
+	}); err != nil {
+		return nil, err
+	}
+	spec.Maps[GCoreSamlesMapName].Pinning = ebpf.PinByName
+
+	// resolved the same way as by Status
+	target, err := resolveTarget(kallsymsPath)
 	if err != nil {
-		samplesMap.Close()
 		return nil, err
 	}
-	kprobe, err := link.Kprobe("do_coredump", prog)
+	spec.Programs["gcoredumper_core_fentry"].AttachTo = target
+
+	b := &BpfBacktracer{pinPath: PinPath(config), target: target}
+	if err := os.MkdirAll(b.pinPath, 0700); err != nil {
+		return nil, err
+	}
+	// a map pinned by this instance has no program filling it
+	// if attaching fails, it must not outlive the attempt
+	mapPath := filepath.Join(b.pinPath, GCoreSamlesMapName)
+	_, err = os.Stat(mapPath)
+	pinned := err == nil
+	err = b.attachFentry(spec)
+	if err != nil {
+		log.Printf("fentry is not available, falling back to kprobe: %v", err)
+		err = b.attachKprobe(spec)
+	}
 	if err != nil {
-		samplesMap.Close()
-		prog.Close()
+		if !pinned || b.replacedMap {
+			if err := os.Remove(mapPath); err != nil && !os.IsNotExist(err) {
+				log.Printf("unable to unpin %s: %v", mapPath, err)
+			}
+		}
 		return nil, err
 	}
-	return &BpfBacktracer{
-		samplesMap: samplesMap,
-		prog:       prog,
-		link:       kprobe,
-	}, nil
+	b.takeOverLink()
+	return b, nil
+}
+
+// load loads objs, replacing the pinned samples map if it is incompatible.
+func (b *BpfBacktracer) load(spec *ebpf.CollectionSpec, objs interface{}) error {
+	opts := &ebpf.CollectionOptions{
+		Maps: ebpf.MapOptions{PinPath: b.pinPath},
+	}
+	err := spec.LoadAndAssign(objs, opts)
+	if errors.Is(err, ebpf.ErrMapIncompatible) {
+		path := filepath.Join(b.pinPath, GCoreSamlesMapName)
+		log.Printf("replacing stale %s: %v", path, err)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		b.replacedMap = true
+		err = spec.LoadAndAssign(objs, opts)
+	}
+	return err
+}
+
+func (b *BpfBacktracer) attachFentry(spec *ebpf.CollectionSpec) error {
+	var objs fentryObjects
+	if err := b.load(spec, &objs); err != nil {
+		return err
+	}
+	l, err := link.AttachTracing(link.TracingOptions{Program: objs.Prog})
+	if err != nil {
+		objs.Prog.Close()
+		objs.backtracerMaps.Close()
+		return err
+	}
+	b.maps, b.prog, b.link = objs.backtracerMaps, objs.Prog, l
+	return nil
+}
+
+func (b *BpfBacktracer) attachKprobe(spec *ebpf.CollectionSpec) error {
+	var objs kprobeObjects
+	if err := b.load(spec, &objs); err != nil {
+		return err
+	}
+	l, err := link.Kprobe(b.target, objs.Prog, nil)
+	if err != nil {
+		objs.Prog.Close()
+		objs.backtracerMaps.Close()
+		return err
+	}
+	b.maps, b.prog, b.link = objs.backtracerMaps, objs.Prog, l
+	return nil
 }
 
+// takeOverLink detaches the link pinned by a previous instance and pins
+// the new one in its place. kprobe links can not be pinned.
+func (b *BpfBacktracer) takeOverLink() {
+	path := filepath.Join(b.pinPath, LinkPinName)
+	if old, err := link.LoadPinnedLink(path, nil); err == nil {
+		if err := old.Unpin(); err != nil {
+			log.Printf("unable to unpin %s: %v", path, err)
+		}
+		old.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("unable to load pinned link %s: %v", path, err)
+	}
+	if err := b.link.Pin(path); err != nil {
+		log.Printf("link is not pinned, restarts lose coverage: %v", err)
+	}
+}
+
+// Close releases the objects. The pinned map and link stay,
+// so the program keeps capturing samples. See Unpin.
 func (b *BpfBacktracer) Close() error {
 	b.link.Close()
 	b.prog.Close()
-	b.samplesMap.Unpin()
-	b.samplesMap.Close()
-	return nil
+	return b.maps.Close()
 }
 
-type Key uint32
+// Unpin removes the pinned map and link,
+// the program is detached on Close.
+func (b *BpfBacktracer) Unpin() error {
+	if err := b.link.Unpin(); err != nil && !errors.Is(err, link.ErrNotSupported) {
+		return err
+	}
+	return b.maps.GcoredSamples.Unpin()
+}
+
+// Sweep deletes samples older than ttl, see Sweep.
+func (b *BpfBacktracer) Sweep(ttl time.Duration) (int, error) {
+	return Sweep(b.maps.GcoredSamples, ttl)
+}
 
+// Backtrace holds valid frames only, innermost first,
+// and the state of the crashing thread.
 type Backtrace struct {
-	Vaddrs [FramesNumber]uint64
+	UserStack   []uint64
+	KernelStack []uint64
+	Regs        Regs
+	// SiCode is si_code of the signal
+	SiCode int32
+	// FaultAddr is si_addr, it is meaningful for
+	// SIGSEGV, SIGBUS, SIGILL, SIGFPE and SIGTRAP only
+	FaultAddr uint64
 }
 
+// Regs are user space registers of the crashing thread.
+type Regs struct {
+	IP uint64
+	SP uint64
+	FP uint64
+}
+
+// UnmarshalBinary decodes struct sample from bpf/backtracer.c,
+// its Go counterpart is generated by bpf2go.
 func (b *Backtrace) UnmarshalBinary(buf []byte) error {
-	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, &b.Vaddrs)
+	var s backtracerSample
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &s); err != nil {
+		return err
+	}
+	if s.UserFrames > MaxFramesNumber || s.KernelFrames > MaxFramesNumber {
+		return fmt.Errorf("invalid number of frames: user %d, kernel %d", s.UserFrames, s.KernelFrames)
+	}
+	b.UserStack = append([]uint64(nil), s.UserStack[:s.UserFrames]...)
+	b.KernelStack = append([]uint64(nil), s.KernelStack[:s.KernelFrames]...)
+	b.Regs = Regs{IP: s.Ip, SP: s.Sp, FP: s.Fp}
+	b.SiCode = s.SiCode
+	b.FaultAddr = s.FaultAddr
+	return nil
 }
 
 var (
 	_ encoding.BinaryUnmarshaler = (*Backtrace)(nil)
 )
 
-func LoadBacktracesMap() (*ebpf.Map, error) {
-	return LoadBacktracesMapFromPath(filepath.Join("/sys/fs/bpf", GCoreSamlesMapName))
+// ErrStaleMap is returned if the pinned samples map is left by
+// an incompatible version.
+var ErrStaleMap = errors.New("stale BPF samples map")
+
+// LoadBacktracesMap opens the samples map pinned to PinPath.
+func LoadBacktracesMap(config *configuration.Config_BPFConfig) (*ebpf.Map, error) {
+	m, err := LoadBacktracesMapFromPath(filepath.Join(PinPath(config), GCoreSamlesMapName))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSamplesMap(m); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
 }
 
 func LoadBacktracesMapFromPath(path string) (*ebpf.Map, error) {
-	return ebpf.LoadPinnedMap(path, &ebpf.LoadPinOptions{
-		ReadOnly: true,
-	})
+	// not read-only, consumers delete samples they have read
+	return ebpf.LoadPinnedMap(path, nil)
+}
+
+// checkSamplesMap verifies the layout of m. Whether a program still fills
+// it is up to Status, scanning loaded programs is too costly per crash.
+func checkSamplesMap(m *ebpf.Map) error {
+	keySize, valueSize := uint32(binary.Size(backtracerKey{})), uint32(binary.Size(backtracerSample{}))
+	if m.KeySize() != keySize || m.ValueSize() != valueSize {
+		return fmt.Errorf("%w: key/value size %d/%d, expected %d/%d", ErrStaleMap, m.KeySize(), m.ValueSize(), keySize, valueSize)
+	}
+	return nil
 }
@@ -0,0 +1,165 @@
+package bpfbacktracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+func TestBacktraceUnmarshalBinary(t *testing.T) {
+	s := backtracerSample{
+		UserFrames:   2,
+		KernelFrames: 3,
+		Ip:           0x1000,
+		Sp:           0x7ff0,
+		Fp:           0x7ff8,
+		SiCode:       1,
+		FaultAddr:    0x10,
+	}
+	s.UserStack[0], s.UserStack[1], s.UserStack[2] = 0x1000, 0x2000, 0xdead
+	s.KernelStack[0], s.KernelStack[1], s.KernelStack[2] = 0xffff1000, 0xffff2000, 0xffff3000
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Backtrace
+	if err := got.UnmarshalBinary(buf.Bytes()); err != nil {
+		t.Fatalf("UnmarshalBinary() = %v", err)
+	}
+	want := Backtrace{
+		UserStack:   []uint64{0x1000, 0x2000},
+		KernelStack: []uint64{0xffff1000, 0xffff2000, 0xffff3000},
+		Regs:        Regs{IP: 0x1000, SP: 0x7ff0, FP: 0x7ff8},
+		SiCode:      1,
+		FaultAddr:   0x10,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("UnmarshalBinary() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBacktraceUnmarshalBinaryInvalid(t *testing.T) {
+	s := backtracerSample{UserFrames: MaxFramesNumber + 1}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &s); err != nil {
+		t.Fatal(err)
+	}
+	var b Backtrace
+	if err := b.UnmarshalBinary(buf.Bytes()); err == nil {
+		t.Error("UnmarshalBinary() = nil, want error")
+	}
+	if err := b.UnmarshalBinary(buf.Bytes()[:16]); err == nil {
+		t.Error("UnmarshalBinary() of a short buffer = nil, want error")
+	}
+}
+
+func TestStackDepth(t *testing.T) {
+	for _, tc := range []struct {
+		config *configuration.Config_BPFConfig
+		want   uint32
+	}{
+		{nil, MaxFramesNumber},
+		{&configuration.Config_BPFConfig{}, MaxFramesNumber},
+		{&configuration.Config_BPFConfig{StackDepth: 16}, 16},
+		{&configuration.Config_BPFConfig{StackDepth: 1000}, MaxFramesNumber},
+	} {
+		if got := stackDepth(tc.config); got != tc.want {
+			t.Errorf("stackDepth(%v) = %d, want %d", tc.config, got, tc.want)
+		}
+	}
+}
+
+func TestCheckSamplesMap(t *testing.T) {
+	spec, err := loadBacktracer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	incompatible, err := ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.LRUHash,
+		KeySize:    4,
+		ValueSize:  32,
+		MaxEntries: 1,
+	})
+	if err != nil {
+		t.Skipf("BPF maps are not available: %v", err)
+	}
+	defer incompatible.Close()
+	if err := checkSamplesMap(incompatible); !errors.Is(err, ErrStaleMap) {
+		t.Errorf("checkSamplesMap() of an incompatible map = %v, want %v", err, ErrStaleMap)
+	}
+
+	var objs kprobeObjects
+	if err := spec.LoadAndAssign(&objs, nil); err != nil {
+		t.Skipf("BPF programs are not available: %v", err)
+	}
+	defer objs.backtracerMaps.Close()
+	if err := checkSamplesMap(objs.GcoredSamples); err != nil {
+		t.Errorf("checkSamplesMap() of a compatible map = %v, want nil", err)
+	}
+}
+
+func TestProgramsUsingMap(t *testing.T) {
+	spec, err := loadBacktracer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var objs kprobeObjects
+	if err := spec.LoadAndAssign(&objs, nil); err != nil {
+		t.Skipf("BPF programs are not available: %v", err)
+	}
+	defer objs.backtracerMaps.Close()
+	info, err := objs.GcoredSamples.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("map IDs are not available")
+	}
+	progs, err := programsUsingMap(id)
+	if err != nil || len(progs) != 1 {
+		t.Fatalf("programsUsingMap() of a map in use = %v, %v, want a program", progs, err)
+	}
+
+	// loaded is not attached
+	progID, ok := progs[0].ID()
+	if !ok {
+		t.Skip("program IDs are not available")
+	}
+	linked, err := linkedPrograms()
+	if err != nil {
+		t.Fatalf("linkedPrograms() = %v", err)
+	}
+	if linked[progID] {
+		t.Errorf("linkedPrograms() = %v, want no link of an unattached program %d", linked, progID)
+	}
+
+	objs.Prog.Close()
+	// the program is released asynchronously
+	deadline := time.Now().Add(5 * time.Second)
+	for len(progs) > 0 && err == nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		progs, err = programsUsingMap(id)
+	}
+	if err != nil || len(progs) > 0 {
+		t.Errorf("programsUsingMap() of an unused map = %v, %v, want none", progs, err)
+	}
+}
+
+func TestPinPath(t *testing.T) {
+	if got := PinPath(nil); got != DefaultPinPath {
+		t.Errorf("PinPath(nil) = %q, want %q", got, DefaultPinPath)
+	}
+	config := &configuration.Config_BPFConfig{PinPath: "/sys/fs/bpf/instance"}
+	if got := PinPath(config); got != config.PinPath {
+		t.Errorf("PinPath() = %q, want %q", got, config.PinPath)
+	}
+}
@@ -0,0 +1,124 @@
+package bpfbacktracer
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+// DefaultSampleTTL is used if BPFConfig.sample_ttl_sec is not set.
+const DefaultSampleTTL = 10 * time.Minute
+
+// SampleTTL returns how long unconsumed samples are kept.
+func SampleTTL(config *configuration.Config_BPFConfig) time.Duration {
+	if ttl := config.GetSampleTtlSec(); ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return DefaultSampleTTL
+}
+
+// MatchWindow is the maximum distance between the capture time of a sample
+// and the dump time. core_pattern %t has a second precision.
+const MatchWindow = 2 * time.Second
+
+// ErrNoBacktrace is returned if no sample matches a crash.
+var ErrNoBacktrace = errors.New("no BPF backtrace")
+
+// bootTime returns the wall clock time of CLOCK_BOOTTIME zero.
+func bootTime() (time.Time, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-time.Duration(ts.Nano())), nil
+}
+
+// keys returns all keys of the samples map.
+func keys(m *ebpf.Map) ([]backtracerKey, error) {
+	var (
+		keys []backtracerKey
+		key  backtracerKey
+		// values are skipped
+		value []byte
+	)
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		keys = append(keys, key)
+	}
+	return keys, iter.Err()
+}
+
+// TakeBacktrace finds the sample of tid captured closest to dumpTime
+// within MatchWindow and deletes it from m.
+func TakeBacktrace(m *ebpf.Map, tid uint32, dumpTime time.Time) (*Backtrace, error) {
+	ks, err := keys(m)
+	if err != nil {
+		return nil, err
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		found bool
+		match backtracerKey
+		best  = MatchWindow + 1
+	)
+	for _, k := range ks {
+		if k.Tid != tid {
+			continue
+		}
+		diff := boot.Add(time.Duration(k.KtimeNs)).Sub(dumpTime)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < best {
+			found, match, best = true, k, diff
+		}
+	}
+	if !found {
+		return nil, ErrNoBacktrace
+	}
+
+	var b Backtrace
+	if err := m.Lookup(&match, &b); err != nil {
+		return nil, err
+	}
+	if err := m.Delete(&match); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Sweep deletes samples captured more than ttl ago, nobody is going to
+// consume them. It returns the number of deleted samples.
+func Sweep(m *ebpf.Map, ttl time.Duration) (int, error) {
+	ks, err := keys(m)
+	if err != nil {
+		return 0, err
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return 0, err
+	}
+	deadline := time.Now().Add(-ttl)
+	var deleted int
+	for _, k := range ks {
+		if boot.Add(time.Duration(k.KtimeNs)).After(deadline) {
+			continue
+		}
+		if err := m.Delete(&k); err != nil {
+			if errors.Is(err, ebpf.ErrKeyNotExist) {
+				continue
+			}
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,98 @@
+package bpfbacktracer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/unix"
+)
+
+func newSamplesMap(t *testing.T) *ebpf.Map {
+	t.Helper()
+	spec, err := loadBacktracer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ebpf.NewMap(spec.Maps[GCoreSamlesMapName])
+	if err != nil {
+		t.Skipf("BPF maps are not available: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+// ktimeAgo returns CLOCK_BOOTTIME d ago.
+func ktimeAgo(t *testing.T, d time.Duration) uint64 {
+	t.Helper()
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		t.Fatal(err)
+	}
+	return uint64(ts.Nano() - d.Nanoseconds())
+}
+
+func putSample(t *testing.T, m *ebpf.Map, key backtracerKey, userStack ...uint64) {
+	t.Helper()
+	s := backtracerSample{UserFrames: uint32(len(userStack))}
+	copy(s.UserStack[:], userStack)
+	if err := m.Put(&key, &s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeBacktrace(t *testing.T) {
+	m := newSamplesMap(t)
+	now := time.Now()
+	// the same TID reused by another process an hour ago
+	putSample(t, m, backtracerKey{Tid: 100, KtimeNs: ktimeAgo(t, time.Hour)}, 0x1)
+	putSample(t, m, backtracerKey{Tid: 100, KtimeNs: ktimeAgo(t, 0)}, 0x2, 0x3)
+	putSample(t, m, backtracerKey{Tid: 200, KtimeNs: ktimeAgo(t, 0)}, 0x4)
+
+	b, err := TakeBacktrace(m, 100, now)
+	if err != nil {
+		t.Fatalf("TakeBacktrace() = %v", err)
+	}
+	want := &Backtrace{UserStack: []uint64{0x2, 0x3}}
+	if diff := cmp.Diff(want, b); diff != "" {
+		t.Errorf("TakeBacktrace() mismatch (-want +got):\n%s", diff)
+	}
+
+	// the sample is consumed
+	if _, err := TakeBacktrace(m, 100, now); err != ErrNoBacktrace {
+		t.Errorf("second TakeBacktrace() = %v, want %v", err, ErrNoBacktrace)
+	}
+	if _, err := TakeBacktrace(m, 300, now); err != ErrNoBacktrace {
+		t.Errorf("TakeBacktrace() of unknown tid = %v, want %v", err, ErrNoBacktrace)
+	}
+	ks, err := keys(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks) != 2 {
+		t.Errorf("%d samples left, want 2", len(ks))
+	}
+}
+
+func TestSweep(t *testing.T) {
+	m := newSamplesMap(t)
+	putSample(t, m, backtracerKey{Tid: 100, KtimeNs: ktimeAgo(t, time.Hour)}, 0x1)
+	putSample(t, m, backtracerKey{Tid: 200, KtimeNs: ktimeAgo(t, 20*time.Minute)}, 0x1)
+	putSample(t, m, backtracerKey{Tid: 300, KtimeNs: ktimeAgo(t, time.Second)}, 0x1)
+
+	n, err := Sweep(m, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Sweep() = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Sweep() = %d, want 2", n)
+	}
+	ks, err := keys(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks) != 1 || ks[0].Tid != 300 {
+		t.Errorf("samples left: %v, want tid 300 only", ks)
+	}
+}
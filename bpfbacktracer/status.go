@@ -0,0 +1,279 @@
+package bpfbacktracer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"golang.org/x/sys/unix"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+// kallsymsPath lists kernel symbols.
+const kallsymsPath = "/proc/kallsyms"
+
+// State describes the objects pinned by gcoredumperbpf.
+type State struct {
+	PinPath string
+	// MapPinned is true if the samples map is pinned
+	MapPinned bool
+	// MapEntries is the number of samples, MapMaxEntries is the capacity
+	MapEntries    int
+	MapMaxEntries uint32
+	// LastUpdate is the capture time of the newest sample,
+	// zero if the map is empty
+	LastUpdate time.Time
+	// LinkPinned is true if the fentry link is pinned,
+	// kprobe links are never pinned
+	LinkPinned bool
+	// Program is the name of a loaded program filling the map, empty if none
+	Program string
+	// Attached is true if a link attaches Program: the pinned fentry link
+	// or a kprobe link held by gcoredumperbpf
+	Attached bool
+	// Target is the first of TargetSymbols the running kernel has,
+	// TargetFound is false if it has none
+	Target      string
+	TargetFound bool
+}
+
+// Healthy reports whether crashes are being captured.
+func (s *State) Healthy() bool {
+	return s.MapPinned && s.Attached && s.TargetFound
+}
+
+// Status inspects the objects pinned to PinPath.
+// Missing objects are reported in State, not as errors.
+func Status(config *configuration.Config_BPFConfig) (*State, error) {
+	s := &State{PinPath: PinPath(config)}
+
+	target, err := resolveTarget(kallsymsPath)
+	switch {
+	case err == nil:
+		s.Target, s.TargetFound = target, true
+	case !errors.Is(err, ErrNoTarget):
+		return nil, err
+	}
+
+	l, err := link.LoadPinnedLink(filepath.Join(s.PinPath, LinkPinName), nil)
+	switch {
+	case err == nil:
+		s.LinkPinned = true
+		l.Close()
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	m, err := LoadBacktracesMapFromPath(filepath.Join(s.PinPath, GCoreSamlesMapName))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+	s.MapPinned = true
+	s.MapMaxEntries = m.MaxEntries()
+
+	if err := s.inspectSamples(m); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *State) inspectSamples(m *ebpf.Map) error {
+	info, err := m.Info()
+	if err != nil {
+		return err
+	}
+	if id, ok := info.ID(); ok {
+		progs, err := programsUsingMap(id)
+		if err != nil {
+			return err
+		}
+		linked, err := linkedPrograms()
+		if err != nil {
+			return err
+		}
+		for _, prog := range progs {
+			if s.Program == "" {
+				s.Program = prog.Name
+			}
+			if progID, ok := prog.ID(); ok && linked[progID] {
+				s.Program, s.Attached = prog.Name, true
+				break
+			}
+		}
+	}
+
+	ks, err := keys(m)
+	if err != nil {
+		return err
+	}
+	s.MapEntries = len(ks)
+	var newest uint64
+	for _, k := range ks {
+		if k.KtimeNs > newest {
+			newest = k.KtimeNs
+		}
+	}
+	if newest > 0 {
+		boot, err := bootTime()
+		if err != nil {
+			return err
+		}
+		s.LastUpdate = boot.Add(time.Duration(newest))
+	}
+	return nil
+}
+
+// ErrNoTarget is returned if the running kernel has none of TargetSymbols.
+var ErrNoTarget = errors.New("no coredump function to attach to")
+
+// resolveTarget returns the first of TargetSymbols listed in kallsyms.
+func resolveTarget(kallsyms string) (string, error) {
+	found, err := kernelSymbols(kallsyms, TargetSymbols)
+	if err != nil {
+		return "", err
+	}
+	for _, symbol := range TargetSymbols {
+		if found[symbol] {
+			return symbol, nil
+		}
+	}
+	return "", fmt.Errorf("%w: none of %s in %s", ErrNoTarget, strings.Join(TargetSymbols, ", "), kallsyms)
+}
+
+// kernelSymbols looks functions up in kallsyms, the result has
+// those of symbols which are found.
+func kernelSymbols(path string, symbols []string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+	found := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// <addr> <type> <name> [module]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && wanted[fields[2]] {
+			found[fields[2]] = true
+		}
+	}
+	return found, scanner.Err()
+}
+
+// programsUsingMap returns loaded programs referencing the map.
+func programsUsingMap(id ebpf.MapID) ([]*ebpf.ProgramInfo, error) {
+	var (
+		progs  []*ebpf.ProgramInfo
+		progID ebpf.ProgramID
+	)
+	for {
+		var err error
+		progID, err = ebpf.ProgramGetNextID(progID)
+		if errors.Is(err, os.ErrNotExist) {
+			return progs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		prog, err := ebpf.NewProgramFromID(progID)
+		if err != nil {
+			// unloaded meanwhile
+			continue
+		}
+		info, err := prog.Info()
+		prog.Close()
+		if err != nil {
+			continue
+		}
+		mapIDs, _ := info.MapIDs()
+		for _, mapID := range mapIDs {
+			if mapID == id {
+				progs = append(progs, info)
+				break
+			}
+		}
+	}
+}
+
+// linkAttr is union bpf_attr of BPF_LINK_GET_NEXT_ID and
+// BPF_LINK_GET_FD_BY_ID, ID is start_id or link_id.
+type linkAttr struct {
+	ID        uint32
+	NextID    uint32
+	OpenFlags uint32
+}
+
+// linkedPrograms returns IDs of programs attached by links, whether the
+// links are pinned or held by a process. cilium/ebpf does not enumerate
+// links: they are walked with the bpf syscall and the program of a link
+// is read from its fdinfo. Kernels without link IDs have no links listed.
+func linkedPrograms() (map[ebpf.ProgramID]bool, error) {
+	progs := make(map[ebpf.ProgramID]bool)
+	var attr linkAttr
+	for {
+		_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_LINK_GET_NEXT_ID, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+		switch errno {
+		case 0:
+		case unix.ENOENT, unix.EINVAL:
+			return progs, nil
+		default:
+			return nil, fmt.Errorf("BPF_LINK_GET_NEXT_ID: %w", errno)
+		}
+		attr.ID, attr.NextID = attr.NextID, 0
+		fd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_LINK_GET_FD_BY_ID, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+		if errno != 0 {
+			// detached meanwhile
+			continue
+		}
+		f, err := os.Open(fmt.Sprintf("/proc/self/fdinfo/%d", fd))
+		if err != nil {
+			unix.Close(int(fd))
+			return nil, err
+		}
+		id, err := parseFdinfoProgID(f)
+		f.Close()
+		unix.Close(int(fd))
+		if err != nil {
+			return nil, err
+		}
+		progs[id] = true
+	}
+}
+
+// parseFdinfoProgID reads prog_id of a link fdinfo.
+func parseFdinfoProgID(r io.Reader) (ebpf.ProgramID, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || key != "prog_id" {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("malformed prog_id: %w", err)
+		}
+		return ebpf.ProgramID(id), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no prog_id in link fdinfo")
+}
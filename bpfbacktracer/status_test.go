@@ -0,0 +1,83 @@
+package bpfbacktracer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+func TestResolveTarget(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		kallsyms string
+		want     string
+		err      error
+	}{
+		{
+			name: "do_coredump",
+			kallsyms: "ffffffff81000000 T _stext\n" +
+				"ffffffff8121b26f t do_coredump.cold\n" +
+				"ffffffff812a0000 T do_coredump\n" +
+				"ffffffffc0000000 t nf_hook [nf_tables]\n",
+			want: "do_coredump",
+		},
+		{
+			name: "vfs_coredump",
+			kallsyms: "ffffffff81000000 T _stext\n" +
+				"ffffffff812a0000 T vfs_coredump\n",
+			want: "vfs_coredump",
+		},
+		{
+			name:     "none",
+			kallsyms: "ffffffff81000000 T _stext\nffffffff8121b26f t do_coredump.cold\n",
+			err:      ErrNoTarget,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kallsyms")
+			if err := os.WriteFile(path, []byte(tc.kallsyms), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := resolveTarget(path)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("resolveTarget() error = %v, want %v", err, tc.err)
+			}
+			if got != tc.want {
+				t.Errorf("resolveTarget() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFdinfoProgID(t *testing.T) {
+	fdinfo := "pos:\t0\nflags:\t02000000\nmnt_id:\t15\nino:\t1057\n" +
+		"link_type:\ttracing\nlink_id:\t7\nprog_tag:\t5d4b1ef1b1a6b9b2\nprog_id:\t42\n" +
+		"attach_type:\t24\n"
+	id, err := parseFdinfoProgID(strings.NewReader(fdinfo))
+	if err != nil || id != 42 {
+		t.Errorf("parseFdinfoProgID() = %d, %v, want 42", id, err)
+	}
+	if _, err := parseFdinfoProgID(strings.NewReader("pos:\t0\n")); err == nil {
+		t.Error("parseFdinfoProgID() without prog_id = nil, want error")
+	}
+}
+
+func TestStatusNotPinned(t *testing.T) {
+	if _, err := os.Stat(kallsymsPath); err != nil {
+		t.Skipf("no kallsyms: %v", err)
+	}
+	s, err := Status(&configuration.Config_BPFConfig{PinPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Status() = %v", err)
+	}
+	if s.MapPinned || s.LinkPinned || s.Program != "" || s.Attached {
+		t.Errorf("Status() = %+v, want nothing pinned", s)
+	}
+	if s.Healthy() {
+		t.Error("Healthy() = true, want false")
+	}
+}
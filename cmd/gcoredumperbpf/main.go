@@ -1,17 +1,87 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/cilium/ebpf/ringbuf"
 	"golang.org/x/sys/unix"
 
 	"github.com/noxiouz/gcoredumper/bpfbacktracer"
+	"github.com/noxiouz/gcoredumper/configuration"
+	"github.com/noxiouz/gcoredumper/configuration/configurator"
+	_ "github.com/noxiouz/gcoredumper/configuration/configurator/localfile"
 )
 
+var (
+	config       = flag.String("cfg", "", "configurator URI <factory>:<path>, e.g. file:/etc/gcoredumper/config.prototxt. The well-known file, then the embedded config, are used if empty")
+	pinPath      = flag.String("pin-path", "", "bpffs directory to pin the samples map and the link to, overrides the config")
+	unpin        = flag.Bool("unpin", false, "remove pinned objects on exit, the program is detached")
+	eventsSocket = flag.String("events", "/run/gcoredumper/events.sock", "unix socket crash events are streamed to as JSON lines, disabled if empty")
+)
+
+func loadConfig(ctx context.Context) (*configuration.Config, error) {
+	// the same chain as the handler, so both agree on the pin path
+	cfg, source, err := configurator.Load(ctx, *config, func(err error) {
+		log.Printf("%v", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("config loaded from %s", source)
+	if *pinPath != "" {
+		if cfg.Bpf == nil {
+			cfg.Bpf = new(configuration.Config_BPFConfig)
+		}
+		cfg.Bpf.PinPath = *pinPath
+	}
+	return cfg, nil
+}
+
+// listenEvents replaces a socket left by a previous instance.
+func listenEvents(path string) (*bpfbacktracer.EventStream, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	stream := bpfbacktracer.NewEventStream(ln)
+	go func() {
+		if err := stream.Serve(); err != nil {
+			log.Printf("event stream failed: %v", err)
+		}
+	}()
+	return stream, nil
+}
+
 func main() {
+	statusMode := len(os.Args) > 1 && os.Args[1] == "status"
+	if statusMode {
+		flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
+	cfg, err := loadConfig(context.Background())
+	if err != nil {
+		log.Fatalf("unable to load config %s: %v", *config, err)
+	}
+	if statusMode {
+		os.Exit(statusCmd(os.Stdout, cfg.Bpf))
+	}
+
 	stopper := make(chan os.Signal, 1)
 	signal.Notify(stopper, os.Interrupt, syscall.SIGTERM)
 
@@ -21,11 +91,68 @@ func main() {
 	}); err != nil {
 		log.Fatalf("setting temporary rlimit: %s", err)
 	}
-	b, err := bpfbacktracer.NewBPFBacktracer()
+	b, err := bpfbacktracer.NewBPFBacktracer(cfg.Bpf)
 	if err != nil {
 		log.Fatalf("backtracker.New failed: %v", err)
 	}
 	defer b.Close()
+	if *unpin {
+		defer func() {
+			if err := b.Unpin(); err != nil {
+				log.Printf("unable to unpin: %v", err)
+			}
+		}()
+	}
 	log.Println("Stand by... Keeping KProbe alive")
-	<-stopper
+
+	events, err := b.Events()
+	if err != nil {
+		log.Fatalf("unable to read crash events: %v", err)
+	}
+	defer events.Close()
+	var stream *bpfbacktracer.EventStream
+	if *eventsSocket != "" {
+		if stream, err = listenEvents(*eventsSocket); err != nil {
+			log.Fatalf("unable to listen %s: %v", *eventsSocket, err)
+		}
+		defer stream.Close()
+	}
+	go func() {
+		for {
+			e, err := events.Read()
+			if err != nil {
+				if errors.Is(err, ringbuf.ErrClosed) {
+					return
+				}
+				log.Printf("unable to read crash event: %v", err)
+				continue
+			}
+			log.Printf("crash: pid %d, tid %d, comm %q, cgroup %d, signal %d", e.Pid, e.Tid, e.Comm, e.CgroupID, e.Signal)
+			if stream == nil {
+				continue
+			}
+			if err := stream.Publish(e); err != nil {
+				log.Printf("unable to publish crash event: %v", err)
+			}
+		}
+	}()
+
+	// Samples of crashes nobody handled, e.g. not dumpable ones,
+	// would otherwise stay until LRU evicts them.
+	ttl := bpfbacktracer.SampleTTL(cfg.Bpf)
+	sweeper := time.NewTicker(ttl)
+	defer sweeper.Stop()
+	for {
+		select {
+		case <-sweeper.C:
+			n, err := b.Sweep(ttl)
+			if err != nil {
+				log.Printf("unable to sweep stale samples: %v", err)
+			} else if n > 0 {
+				log.Printf("%d stale samples removed", n)
+			}
+		case <-stopper:
+			return
+		}
+	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/noxiouz/gcoredumper/bpfbacktracer"
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+// statusCmd prints the state of pinned objects. The exit code is non-zero
+// unless crashes are being captured, so it fits systemd ExecStartPost.
+func statusCmd(w io.Writer, config *configuration.Config_BPFConfig) int {
+	s, err := bpfbacktracer.Status(config)
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return 2
+	}
+	fmt.Fprintf(w, "pin path: %s\n", s.PinPath)
+	if s.TargetFound {
+		fmt.Fprintf(w, "target: %s\n", s.Target)
+	} else {
+		fmt.Fprintf(w, "target: none of %s\n", strings.Join(bpfbacktracer.TargetSymbols, ", "))
+	}
+	fmt.Fprintf(w, "map pinned: %t\n", s.MapPinned)
+	fmt.Fprintf(w, "map entries: %d/%d\n", s.MapEntries, s.MapMaxEntries)
+	if s.LastUpdate.IsZero() {
+		fmt.Fprintf(w, "last update: never\n")
+	} else {
+		fmt.Fprintf(w, "last update: %s\n", s.LastUpdate.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "link pinned: %t\n", s.LinkPinned)
+	if s.Program == "" {
+		fmt.Fprintf(w, "program: none\n")
+	} else {
+		fmt.Fprintf(w, "program: %s\n", s.Program)
+	}
+	fmt.Fprintf(w, "attached: %t\n", s.Attached)
+
+	if !s.Healthy() {
+		fmt.Fprintf(w, "status: unhealthy\n")
+		return 1
+	}
+	fmt.Fprintf(w, "status: ok\n")
+	return 0
+}
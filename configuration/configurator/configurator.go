@@ -2,7 +2,10 @@ package configurator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"strings"
 	"sync"
 
 	"github.com/noxiouz/gcoredumper/configuration"
@@ -43,3 +46,62 @@ func Open(name, path string) (Configurator, error) {
 	}
 	return factory.Open(path)
 }
+
+// ParseURI splits a configurator URI of the form <factory>:<path>,
+// e.g. "file:/etc/gcoredumper/config.prototxt" or "embed:".
+// The path part is optional.
+func ParseURI(uri string) (name string, path string, err error) {
+	name, path, _ = strings.Cut(uri, ":")
+	if name == "" {
+		return "", "", fmt.Errorf("malformed configurator URI %q: factory name is empty", uri)
+	}
+	return name, path, nil
+}
+
+// OpenURI opens a configurator described by <factory>:<path> URI.
+func OpenURI(uri string) (Configurator, error) {
+	name, path, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return Open(name, path)
+}
+
+const (
+	// WellKnownURI is tried when no URI is given or it cannot be loaded.
+	WellKnownURI = "file:/etc/gcoredumper/config.prototxt"
+	// EmbeddedURI is the last resort.
+	EmbeddedURI = "embed:"
+)
+
+// Load walks the fallback chain: uri if not empty, WellKnownURI,
+// EmbeddedURI. The first configurator that yields a config wins and its
+// URI is returned as the source. Failures of uri, and failures of the
+// others except a missing file, are passed to onError if it is not nil.
+func Load(ctx context.Context, uri string, onError func(error)) (cfg *configuration.Config, source string, err error) {
+	var candidates []string
+	if uri != "" {
+		candidates = append(candidates, uri)
+	}
+	candidates = append(candidates, WellKnownURI, EmbeddedURI)
+
+	var lastErr error
+	for _, candidate := range candidates {
+		cfg, err := func() (*configuration.Config, error) {
+			c, err := OpenURI(candidate)
+			if err != nil {
+				return nil, err
+			}
+			return c.Get(ctx)
+		}()
+		if err != nil {
+			lastErr = fmt.Errorf("config %s: %w", candidate, err)
+			if onError != nil && (candidate == uri || !errors.Is(err, fs.ErrNotExist)) {
+				onError(lastErr)
+			}
+			continue
+		}
+		return cfg, candidate, nil
+	}
+	return nil, "", lastErr
+}
@@ -0,0 +1,73 @@
+package configurator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+type staticConfigurator struct {
+	cfg *configuration.Config
+	err error
+}
+
+func (s staticConfigurator) Get(ctx context.Context) (*configuration.Config, error) {
+	return s.cfg, s.err
+}
+
+// registerStatic registers a factory whose configurators return cfg and err
+func registerStatic(name string, cfg *configuration.Config, err error) {
+	Register(name, FactoryFunc(func(path string) (Configurator, error) {
+		return staticConfigurator{cfg: cfg, err: err}, nil
+	}))
+}
+
+func TestLoad(t *testing.T) {
+	embedded := &configuration.Config{CorefilesDirectory: "/embedded"}
+	wellKnown := &configuration.Config{CorefilesDirectory: "/well-known"}
+	registerStatic("embed", embedded, nil)
+	registerStatic("broken", nil, errors.New("parse error"))
+	registerStatic("missing", nil, fmt.Errorf("open: %w", fs.ErrNotExist))
+
+	for _, tc := range []struct {
+		name       string
+		uri        string
+		wellKnown  error
+		wantSource string
+		wantDir    string
+		wantErrors int
+	}{
+		{name: "explicit", uri: "embed:", wantSource: "embed:", wantDir: "/embedded"},
+		{name: "missing explicit is reported", uri: "missing:", wellKnown: fs.ErrNotExist, wantSource: EmbeddedURI, wantDir: "/embedded", wantErrors: 1},
+		{name: "broken explicit falls back", uri: "broken:", wantSource: WellKnownURI, wantDir: "/well-known", wantErrors: 1},
+		{name: "missing well-known is not reported", wellKnown: fs.ErrNotExist, wantSource: EmbeddedURI, wantDir: "/embedded"},
+		{name: "broken well-known is reported", wellKnown: errors.New("parse error"), wantSource: EmbeddedURI, wantDir: "/embedded", wantErrors: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			registerStatic("file", wellKnown, tc.wellKnown)
+			var errs []error
+			cfg, source, err := Load(context.Background(), tc.uri, func(err error) {
+				errs = append(errs, err)
+			})
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if source != tc.wantSource || cfg.CorefilesDirectory != tc.wantDir {
+				t.Errorf("Load() = %q from %q, want %q from %q", cfg.CorefilesDirectory, source, tc.wantDir, tc.wantSource)
+			}
+			if len(errs) != tc.wantErrors {
+				t.Errorf("reported %v, want %d errors", errs, tc.wantErrors)
+			}
+		})
+	}
+
+	registerStatic("file", nil, fs.ErrNotExist)
+	registerStatic("embed", nil, errors.New("corrupted"))
+	if _, _, err := Load(context.Background(), "", nil); err == nil {
+		t.Error("Load() = nil, want error when every configurator fails")
+	}
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// Cgroup is a line of /proc/<pid>/cgroup. HierarchyID is 0 and
+// Controllers are empty for the cgroup v2 unified hierarchy.
+type Cgroup struct {
+	HierarchyID int
+	Controllers []string
+	Path        string
+}
+
+// IsUnified reports whether c belongs to the cgroup v2 hierarchy.
+func (c Cgroup) IsUnified() bool {
+	return c.HierarchyID == 0 && len(c.Controllers) == 0
+}
+
+func (c Cgroup) String() string {
+	return fmt.Sprintf("%d:%s:%s", c.HierarchyID, strings.Join(c.Controllers, ","), c.Path)
+}
+
+func readCgroups(procFs afero.Fs) ([]Cgroup, error) {
+	f, err := procFs.Open("cgroup")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseCgroups(f)
+}
+
+func parseCgroups(r io.Reader) ([]Cgroup, error) {
+	var (
+		cgroups []Cgroup
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		// the path may contain ':'
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed cgroup %q", line)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed cgroup %q: %w", line, err)
+		}
+		cgroup := Cgroup{HierarchyID: id, Path: fields[2]}
+		if fields[1] != "" {
+			cgroup.Controllers = strings.Split(fields[1], ",")
+		}
+		cgroups = append(cgroups, cgroup)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cgroups, nil
+}
+
+// Container identifies the container the process runs in.
+type Container struct {
+	// Runtime is one of docker, containerd, cri-o, podman, kubernetes
+	// if the runtime of a pod can not be told, or systemd for other
+	// systemd scopes
+	Runtime string
+	// ID is the container ID or the name of the systemd scope
+	ID string
+}
+
+// containerPatterns match a cgroup path component named by a runtime.
+// The systemd cgroup driver names scopes <prefix>-<id>.scope,
+// cgroupfs ones use plain IDs in a directory of the runtime.
+var containerPatterns = []struct {
+	runtime string
+	re      *regexp.Regexp
+}{
+	{"docker", regexp.MustCompile(`^docker-([0-9a-f]{64})\.scope$`)},
+	{"containerd", regexp.MustCompile(`^cri-containerd-([0-9a-f]{64})\.scope$`)},
+	{"cri-o", regexp.MustCompile(`^crio-([0-9a-f]{64})\.scope$`)},
+	{"podman", regexp.MustCompile(`^libpod-([0-9a-f]{64})(\.scope)?$`)},
+}
+
+var containerID = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// detectContainer derives the container from cgroup paths. Components
+// are looked at deepest first, e.g. podman nests the container processes
+// into <scope>/container.
+func detectContainer(cgroups []Cgroup) *Container {
+	// the unified hierarchy first, v1 ones are the same for runtimes
+	// and may be left at the root by cgroup namespaces
+	ordered := make([]Cgroup, 0, len(cgroups))
+	for _, c := range cgroups {
+		if c.IsUnified() {
+			ordered = append([]Cgroup{c}, ordered...)
+		} else {
+			ordered = append(ordered, c)
+		}
+	}
+	var scope *Container
+	for _, c := range ordered {
+		if container := containerFromPath(c.Path); container != nil {
+			if container.Runtime != "systemd" {
+				return container
+			}
+			if scope == nil {
+				scope = container
+			}
+		}
+	}
+	return scope
+}
+
+func containerFromPath(path string) *Container {
+	components := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(components) - 1; i >= 0; i-- {
+		component := components[i]
+		for _, p := range containerPatterns {
+			if m := p.re.FindStringSubmatch(component); m != nil {
+				return &Container{Runtime: p.runtime, ID: m[1]}
+			}
+		}
+		if containerID.MatchString(component) && i > 0 {
+			switch parent := components[i-1]; {
+			case parent == "docker":
+				return &Container{Runtime: "docker", ID: component}
+			case strings.HasPrefix(components[0], "kubepods"):
+				return &Container{Runtime: "kubernetes", ID: component}
+			}
+		}
+	}
+	for i := len(components) - 1; i >= 0; i-- {
+		if strings.HasSuffix(components[i], ".scope") {
+			return &Container{Runtime: "systemd", ID: components[i]}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+const (
+	testContainerID  = "3f4b8c1d2e5a6978a0b1c2d3e4f5061728394a5b6c7d8e9f0a1b2c3d4e5f6071"
+	testContainerID2 = "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a29180f7e6d5c4b3a2918f7e6d5c4b3"
+)
+
+func TestReadCgroups(t *testing.T) {
+	content := "12:pids:/docker/" + testContainerID + "\n" +
+		"11:cpu,cpuacct:/docker/" + testContainerID + "\n" +
+		"1:name=systemd:/docker/" + testContainerID + "\n" +
+		"0::/system.slice/docker-" + testContainerID + ".scope\n" +
+		"0::/path:with:colons\n"
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "cgroup", []byte(content), 0444); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readCgroups(procFs)
+	if err != nil {
+		t.Fatalf("readCgroups() = %v", err)
+	}
+	want := []Cgroup{
+		{HierarchyID: 12, Controllers: []string{"pids"}, Path: "/docker/" + testContainerID},
+		{HierarchyID: 11, Controllers: []string{"cpu", "cpuacct"}, Path: "/docker/" + testContainerID},
+		{HierarchyID: 1, Controllers: []string{"name=systemd"}, Path: "/docker/" + testContainerID},
+		{HierarchyID: 0, Path: "/system.slice/docker-" + testContainerID + ".scope"},
+		{HierarchyID: 0, Path: "/path:with:colons"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readCgroups() mismatch (-want +got):\n%s", diff)
+	}
+	if !got[3].IsUnified() || got[0].IsUnified() {
+		t.Error("IsUnified() does not tell v2 from v1")
+	}
+	for i, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		if got[i].String() != line {
+			t.Errorf("String() = %q, want %q", got[i].String(), line)
+		}
+	}
+
+	if _, err := parseCgroups(strings.NewReader("x:pids:/\n")); err == nil {
+		t.Error("parseCgroups() = nil, want error for a malformed hierarchy ID")
+	}
+	if _, err := parseCgroups(strings.NewReader("0:/\n")); err == nil {
+		t.Error("parseCgroups() = nil, want error for a missing field")
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		cgroups string
+		want    *Container
+	}{
+		{
+			name:    "docker cgroupfs v1",
+			cgroups: "12:pids:/docker/" + testContainerID + "\n1:name=systemd:/docker/" + testContainerID,
+			want:    &Container{Runtime: "docker", ID: testContainerID},
+		},
+		{
+			name:    "docker systemd v2",
+			cgroups: "0::/system.slice/docker-" + testContainerID + ".scope",
+			want:    &Container{Runtime: "docker", ID: testContainerID},
+		},
+		{
+			name:    "containerd kubernetes systemd",
+			cgroups: "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234.slice/cri-containerd-" + testContainerID + ".scope",
+			want:    &Container{Runtime: "containerd", ID: testContainerID},
+		},
+		{
+			name:    "cri-o",
+			cgroups: "0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod1234.slice/crio-" + testContainerID + ".scope",
+			want:    &Container{Runtime: "cri-o", ID: testContainerID},
+		},
+		{
+			name:    "kubernetes cgroupfs",
+			cgroups: "11:memory:/kubepods/burstable/pod0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0/" + testContainerID,
+			want:    &Container{Runtime: "kubernetes", ID: testContainerID},
+		},
+		{
+			name:    "podman rootless",
+			cgroups: "0::/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testContainerID + ".scope/container",
+			want:    &Container{Runtime: "podman", ID: testContainerID},
+		},
+		{
+			name:    "podman cgroupfs",
+			cgroups: "3:memory:/libpod_parent/libpod-" + testContainerID,
+			want:    &Container{Runtime: "podman", ID: testContainerID},
+		},
+		{
+			name:    "systemd scope",
+			cgroups: "0::/user.slice/user-1000.slice/session-2.scope",
+			want:    &Container{Runtime: "systemd", ID: "session-2.scope"},
+		},
+		{
+			name: "unified hierarchy first",
+			cgroups: "12:pids:/docker/" + testContainerID2 + "\n" +
+				"0::/system.slice/docker-" + testContainerID + ".scope",
+			want: &Container{Runtime: "docker", ID: testContainerID},
+		},
+		{
+			name: "container beats systemd scope",
+			cgroups: "0::/user.slice/user-1000.slice/session-2.scope\n" +
+				"1:name=systemd:/docker/" + testContainerID,
+			want: &Container{Runtime: "docker", ID: testContainerID},
+		},
+		{
+			name:    "service",
+			cgroups: "0::/system.slice/sshd.service",
+		},
+		{
+			name:    "root",
+			cgroups: "0::/",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cgroups, err := parseCgroups(strings.NewReader(tc.cgroups))
+			if err != nil {
+				t.Fatalf("parseCgroups() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, detectContainer(cgroups)); diff != "" {
+				t.Errorf("detectContainer() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
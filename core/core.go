@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -18,6 +19,7 @@ import (
 	"github.com/noxiouz/gcoredumper/configuration"
 	"github.com/noxiouz/gcoredumper/dumper"
 	"github.com/noxiouz/gcoredumper/report"
+	"github.com/noxiouz/gcoredumper/symbolizer"
 )
 
 type Dumpable int
@@ -60,6 +62,15 @@ type SystemInput struct {
 	Signal syscall.Signal
 	// Input stream
 	Stream io.ReadCloser
+	// Filesystem /proc/<pid> is read from. afero.NewOsFs() if nil.
+	Filesystem afero.Fs
+}
+
+func (si *SystemInput) filesystem() afero.Fs {
+	if si.Filesystem == nil {
+		return afero.NewOsFs()
+	}
+	return si.Filesystem
 }
 
 func skipCoredump() bool {
@@ -74,31 +85,44 @@ func Run(ctx context.Context, si SystemInput, config *configuration.Config) erro
 	reporter := report.R(ctx)
 	reporter.AddInt("signal", int64(si.Signal))
 
-	pi, err := NewProcessInfo(ctx, si.InitialPid, si.NsPid, si.InitialTid, si.NsTid, afero.NewOsFs())
+	pi, err := NewProcessInfo(ctx, si.InitialPid, si.NsPid, si.InitialTid, si.NsTid, si.filesystem())
 	if err != nil {
 		return err
 	}
 
-	// BPF backtraces
+	// BPF backtraces are optional, they never prevent the dump
 	err = func() error {
-		m, err := bpfbacktracer.LoadBacktracesMap()
+		m, err := bpfbacktracer.LoadBacktracesMap(config.GetBpf())
 		if err != nil {
 			if os.IsNotExist(errors.Unwrap(err)) {
 				return nil
 			}
+			if errors.Is(err, bpfbacktracer.ErrStaleMap) {
+				log.Printf("BPF backtraces are not available: %v", err)
+				reporter.AddError("bpf.map.stale", err)
+				return nil
+			}
 			return err
 		}
 		defer m.Close()
 
-		var b bpfbacktracer.Backtrace
-		m.Lookup(bpfbacktracer.Key(si.InitialTid), &b)
-		for _, vaddr := range b.Vaddrs {
-			log.Printf("Addr %x", vaddr)
+		b, err := bpfbacktracer.TakeBacktrace(m, uint32(si.InitialTid), si.DumpTime)
+		if err != nil {
+			log.Printf("no BPF backtrace for %d: %v", si.InitialTid, err)
+			return nil
 		}
+		addCrashState(reporter, si.Signal, b)
+		if len(b.KernelStack) > 0 {
+			reporter.AddStackTrace("stacktrace.kernel", kernelStackTrace(b.KernelStack))
+		}
+		s := symbolizer.New(pi.procFs, pi.Mappings())
+		defer s.Close()
+		reporter.AddStackTrace("stacktrace.user", s.Symbolize(b.UserStack))
 		return nil
 	}()
 	if err != nil {
-		return err
+		log.Printf("BPF backtraces are not available: %v", err)
+		reporter.AddError("bpf.error", err)
 	}
 
 	var actions = []Action{
@@ -113,12 +137,59 @@ func Run(ctx context.Context, si SystemInput, config *configuration.Config) erro
 	}
 	g.Wait()
 
+	// the corefile just written is never evicted by retention
+	enforceRetention := func(corefile string) {
+		retention := config.GetDumper().GetRetention()
+		if retention == nil {
+			return
+		}
+		if _, err := dumper.NewRetention(afero.NewOsFs()).Enforce(ctx, config.CorefilesDirectory, retention, corefile); err != nil {
+			log.Printf("retention failed: %v", err)
+			reporter.AddError("retention.error", err)
+		}
+	}
+
+	var corefile string
 	if si.PrGetDumpable.AllowCoreDump() {
-		if _, err := dumper.New(afero.NewOsFs()).Dump(ctx, si.Stream, filepath.Join(config.CorefilesDirectory, pi.CorefileName()), config.Dumper); err != nil {
+		// frees space first, a full disk fails the dump otherwise
+		enforceRetention("")
+		d := dumper.New(afero.NewOsFs())
+		corefile, err = d.Dump(ctx, si.Stream, filepath.Join(config.CorefilesDirectory, pi.CorefileName()), config.Dumper)
+		if err != nil {
 			return err
 		}
+		if _, err := d.WriteSidecar(ctx, corefile); err != nil {
+			log.Printf("unable to write sidecar: %v", err)
+			reporter.AddError("core.sidecar.error", err)
+		}
 	} else {
 		reporter.AddString("dump.status", "skipped")
 	}
+
+	enforceRetention(corefile)
 	return nil
 }
+
+// addCrashState reports registers and siginfo captured at do_coredump.
+func addCrashState(reporter *report.Report, signal syscall.Signal, b *bpfbacktracer.Backtrace) {
+	reporter.AddInt("signal.code", int64(b.SiCode))
+	switch signal {
+	case syscall.SIGSEGV, syscall.SIGBUS, syscall.SIGILL, syscall.SIGFPE, syscall.SIGTRAP:
+		reporter.AddString("fault.addr", fmt.Sprintf("0x%x", b.FaultAddr))
+	}
+	reporter.AddString("regs.ip", fmt.Sprintf("0x%x", b.Regs.IP))
+	reporter.AddString("regs.sp", fmt.Sprintf("0x%x", b.Regs.SP))
+	reporter.AddString("regs.fp", fmt.Sprintf("0x%x", b.Regs.FP))
+}
+
+// kernelStackTrace keeps raw addresses, kernel symbols are not resolved.
+func kernelStackTrace(vaddrs []uint64) *report.StackTrace {
+	st := new(report.StackTrace)
+	for _, vaddr := range vaddrs {
+		st.Frames = append(st.Frames, &report.StackTrace_Frame{
+			Addr:   vaddr,
+			Module: "[kernel]",
+		})
+	}
+	return st
+}
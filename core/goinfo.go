@@ -0,0 +1,60 @@
+package core
+
+import (
+	"debug/buildinfo"
+	"runtime/debug"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+// goBuildSettings are reported as go.build.<key> lowercased and without
+// the leading dash. The rest such as -ldflags are left out as they are
+// long and may hold secrets.
+var goBuildSettings = []string{
+	"-compiler",
+	"-race",
+	"-tags",
+	"-trimpath",
+	"CGO_ENABLED",
+	"GOARCH",
+	"GOOS",
+	"GOAMD64",
+	"GOARM",
+	"GOARM64",
+}
+
+// readGoBuildInfo reads the build info embedded into a Go executable.
+// It fails for binaries built by other toolchains.
+func readGoBuildInfo(procFs afero.Fs) (*debug.BuildInfo, error) {
+	exe, err := procFs.Open("exe")
+	if err != nil {
+		return nil, err
+	}
+	defer exe.Close()
+	return buildinfo.Read(exe)
+}
+
+func addGoBuildInfo(rep *report.Report, info *debug.BuildInfo) {
+	rep.AddString("go.version", info.GoVersion)
+	rep.AddString("go.main.path", info.Main.Path)
+	if info.Main.Version != "" {
+		rep.AddString("go.main.version", info.Main.Version)
+	}
+	settings := make(map[string]string, len(info.Settings))
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	for _, key := range []string{"vcs", "vcs.revision", "vcs.time", "vcs.modified"} {
+		if value, ok := settings[key]; ok {
+			rep.AddString("go."+key, value)
+		}
+	}
+	for _, key := range goBuildSettings {
+		if value, ok := settings[key]; ok {
+			rep.AddString("go.build."+strings.ToLower(strings.TrimPrefix(key, "-")), value)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+func TestReadGoBuildInfo(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "exe", content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	info, err := readGoBuildInfo(procFs)
+	if err != nil {
+		t.Fatalf("readGoBuildInfo() = %v", err)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %q, want %q", info.GoVersion, runtime.Version())
+	}
+
+	if err := afero.WriteFile(procFs, "exe", []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readGoBuildInfo(procFs); err == nil {
+		t.Error("readGoBuildInfo() = nil, want error for a non Go executable")
+	}
+}
+
+func TestAddGoBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.18.3",
+		Main: debug.Module{
+			Path:    "github.com/noxiouz/gcoredumper",
+			Version: "(devel)",
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "-ldflags", Value: "-X main.token=secret"},
+			{Key: "-tags", Value: "netgo"},
+			{Key: "CGO_ENABLED", Value: "0"},
+			{Key: "GOARCH", Value: "amd64"},
+			{Key: "GOOS", Value: "linux"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "c2dc9b6d1e0f"},
+			{Key: "vcs.time", Value: "2022-06-01T10:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	rep := report.New()
+	addGoBuildInfo(rep, info)
+
+	got := make(map[string]string)
+	for _, record := range rep.Records() {
+		got[record.Name] = record.GetStr()
+	}
+	want := map[string]string{
+		"go.version":           "go1.18.3",
+		"go.main.path":         "github.com/noxiouz/gcoredumper",
+		"go.main.version":      "(devel)",
+		"go.vcs":               "git",
+		"go.vcs.revision":      "c2dc9b6d1e0f",
+		"go.vcs.time":          "2022-06-01T10:00:00Z",
+		"go.vcs.modified":      "true",
+		"go.build.compiler":    "gc",
+		"go.build.tags":        "netgo",
+		"go.build.cgo_enabled": "0",
+		"go.build.goarch":      "amd64",
+		"go.build.goos":        "linux",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("addGoBuildInfo() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"debug/elf"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+	"github.com/noxiouz/gcoredumper/utils/buildid"
+	"github.com/noxiouz/gcoredumper/utils/procmaps"
+)
+
+// mappingDeletedSuffix is appended to paths of unlinked files in maps
+const mappingDeletedSuffix = " (deleted)"
+
+// Module is a file with executable code mapped into the process,
+// i.e. the binary or a shared object.
+type Module struct {
+	Path    string
+	Deleted bool
+	// Start and End cover all mappings of the file
+	Start uint64
+	End   uint64
+	Dev   string
+	Inode uint64
+	// Mappings of the file with their permissions and offsets
+	Mappings []procmaps.Mapping
+	// BuildID is empty if it could not be read
+	BuildID string
+}
+
+// readMappings parses maps of the process. procFs is rooted at /proc/<pid>.
+func readMappings(procFs afero.Fs) ([]procmaps.Mapping, error) {
+	f, err := procFs.Open("maps")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return procmaps.Parse(f)
+}
+
+// fileID identifies a mapped file regardless of the path it is mapped by
+type fileID struct {
+	dev   string
+	inode uint64
+}
+
+// groupModules groups file backed mappings by device and inode, so a file
+// mapped by several paths is reported once. Files without executable
+// mappings, e.g. locale archives, are skipped. Modules are ordered by Start.
+func groupModules(mappings []procmaps.Mapping) []Module {
+	var (
+		modules []Module
+		index   = make(map[fileID]int)
+	)
+	for _, m := range mappings {
+		if !m.IsFile() {
+			continue
+		}
+		id := fileID{dev: m.Dev, inode: m.Inode}
+		i, ok := index[id]
+		if !ok {
+			i = len(modules)
+			index[id] = i
+			modules = append(modules, Module{
+				Path:    strings.TrimSuffix(m.Path, mappingDeletedSuffix),
+				Deleted: strings.HasSuffix(m.Path, mappingDeletedSuffix),
+				Start:   m.Start,
+				End:     m.End,
+				Dev:     m.Dev,
+				Inode:   m.Inode,
+			})
+		}
+		module := &modules[i]
+		if m.Start < module.Start {
+			module.Start = m.Start
+		}
+		if m.End > module.End {
+			module.End = m.End
+		}
+		module.Mappings = append(module.Mappings, m)
+	}
+
+	executable := modules[:0]
+	for _, module := range modules {
+		for _, m := range module.Mappings {
+			if m.IsExecutable() {
+				executable = append(executable, module)
+				break
+			}
+		}
+	}
+	return executable
+}
+
+// mapFilesPath returns the map_files/ entry of a mapping. It refers to
+// the mapped file itself, even if it is deleted or belongs to another
+// mount namespace.
+func mapFilesPath(m procmaps.Mapping) string {
+	return fmt.Sprintf("map_files/%x-%x", m.Start, m.End)
+}
+
+// readBuildID reads the build ID of a module through map_files/.
+// root/ of the process is the fallback, it resolves the path in the mount
+// namespace of the process but misses deleted files.
+func readBuildID(procFs afero.Fs, module *Module) (string, error) {
+	f, err := procFs.Open(mapFilesPath(module.Mappings[0]))
+	if err != nil {
+		var rootErr error
+		if f, rootErr = procFs.Open(path.Join("root", module.Path)); rootErr != nil {
+			return "", err
+		}
+	}
+	defer f.Close()
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return "", err
+	}
+	return buildid.New(ef)
+}
+
+// modulesRecord converts modules to a report record.
+func modulesRecord(modules []Module) *report.Modules {
+	r := new(report.Modules)
+	for _, module := range modules {
+		r.Modules = append(r.Modules, &report.Modules_Module{
+			Path:    module.Path,
+			Start:   module.Start,
+			End:     module.End,
+			Inode:   module.Inode,
+			BuildId: module.BuildID,
+			Deleted: module.Deleted,
+		})
+	}
+	return r
+}
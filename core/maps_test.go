@@ -0,0 +1,120 @@
+package core
+
+import (
+	"debug/elf"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/utils/buildid"
+	"github.com/noxiouz/gcoredumper/utils/procmaps"
+)
+
+const testMaps = `55d0c0a00000-55d0c0a02000 r--p 00000000 fd:01 1001 /usr/bin/foo
+55d0c0a02000-55d0c0a08000 r-xp 00002000 fd:01 1001 /usr/bin/foo
+55d0c0a08000-55d0c0a0a000 rw-p 00008000 fd:01 1001 /usr/bin/foo
+55d0c1000000-55d0c1021000 rw-p 00000000 00:00 0 [heap]
+7f0000000000-7f0000100000 r--p 00000000 fd:01 2002 /usr/lib/locale/locale-archive
+7f0000200000-7f0000228000 r--p 00000000 fd:01 3003 /usr/lib/libc.so.6
+7f0000228000-7f00003bd000 r-xp 00028000 fd:01 3003 /usr/lib/libc.so.6
+7f00003bd000-7f00003c1000 r--p 001bc000 fd:01 3003 /usr/lib64/libc.so.6
+7f0000400000-7f0000401000 r-xp 00000000 fd:01 4004 /tmp/libplugin.so (deleted)
+7ffd00000000-7ffd00002000 r-xp 00000000 00:00 0 [vdso]
+`
+
+func TestGroupModules(t *testing.T) {
+	mappings, err := procmaps.Parse(strings.NewReader(testMaps))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := groupModules(mappings)
+	want := []Module{
+		{
+			Path:     "/usr/bin/foo",
+			Start:    0x55d0c0a00000,
+			End:      0x55d0c0a0a000,
+			Dev:      "fd:01",
+			Inode:    1001,
+			Mappings: mappings[0:3],
+		},
+		{
+			// the same file mapped by two paths
+			Path:     "/usr/lib/libc.so.6",
+			Start:    0x7f0000200000,
+			End:      0x7f00003c1000,
+			Dev:      "fd:01",
+			Inode:    3003,
+			Mappings: mappings[5:8],
+		},
+		{
+			Path:     "/tmp/libplugin.so",
+			Deleted:  true,
+			Start:    0x7f0000400000,
+			End:      0x7f0000401000,
+			Dev:      "fd:01",
+			Inode:    4004,
+			Mappings: mappings[8:9],
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("groupModules() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadBuildID(t *testing.T) {
+	// any ELF file with a build ID does, the test binary is at hand
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ef, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBuildID, err := buildid.New(ef)
+	ef.Close()
+	if err != nil {
+		t.Skipf("test binary has no build id: %v", err)
+	}
+	content, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "maps", []byte(testMaps), 0444); err != nil {
+		t.Fatal(err)
+	}
+	// found by path in the mount namespace of the process
+	if err := afero.WriteFile(procFs, "root/usr/lib/libc.so.6", content, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// deleted, reachable through map_files only
+	if err := afero.WriteFile(procFs, "map_files/7f0000400000-7f0000401000", content, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	mappings, err := readMappings(procFs)
+	if err != nil {
+		t.Fatalf("readMappings() = %v", err)
+	}
+	modules := groupModules(mappings)
+	if len(modules) != 3 {
+		t.Fatalf("groupModules() returned %d modules, want 3", len(modules))
+	}
+	if _, err := readBuildID(procFs, &modules[0]); err == nil {
+		t.Errorf("readBuildID(%s) = nil, want error for a missing file", modules[0].Path)
+	}
+	for _, module := range modules[1:] {
+		got, err := readBuildID(procFs, &module)
+		if err != nil {
+			t.Fatalf("readBuildID(%s) = %v", module.Path, err)
+		}
+		if got != wantBuildID {
+			t.Errorf("readBuildID(%s) = %q, want %q", module.Path, got, wantBuildID)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// namespaceTypes are links of /proc/<pid>/ns, the ones missing
+// in older kernels are skipped
+var namespaceTypes = []string{"cgroup", "ipc", "mnt", "net", "pid", "time", "user", "uts"}
+
+// Namespaces are inode numbers of namespaces keyed by type, e.g. "pid".
+type Namespaces map[string]uint64
+
+func readNamespaces(procFs afero.Fs) (Namespaces, error) {
+	linkReader, ok := procFs.(afero.LinkReader)
+	if !ok {
+		return nil, errors.New("readlink is not supported")
+	}
+	namespaces := make(Namespaces)
+	for _, typ := range namespaceTypes {
+		link, err := linkReader.ReadlinkIfPossible(path.Join("ns", typ))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		inode, err := parseNamespaceLink(typ, link)
+		if err != nil {
+			return nil, err
+		}
+		namespaces[typ] = inode
+	}
+	return namespaces, nil
+}
+
+// parseNamespaceLink parses <type>:[<inode>]
+func parseNamespaceLink(typ string, link string) (uint64, error) {
+	prefix := typ + ":["
+	if !strings.HasPrefix(link, prefix) || !strings.HasSuffix(link, "]") {
+		return 0, fmt.Errorf("malformed namespace link %q", link)
+	}
+	inode, err := strconv.ParseUint(link[len(prefix):len(link)-1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed namespace link %q: %w", link, err)
+	}
+	return inode, nil
+}
+
+// differs reports whether namespaces of type typ are known and differ.
+func (n Namespaces) differs(other Namespaces, typ string) (differs bool, known bool) {
+	inode, ok := n[typ]
+	otherInode, otherOk := other[typ]
+	if !ok || !otherOk {
+		return false, false
+	}
+	return inode != otherInode, true
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+// writeNamespaces creates ns links of <dir>/proc/<pid>,
+// dangling ones as the targets are not files
+func writeNamespaces(t *testing.T, dir string, pid string, links map[string]string) {
+	t.Helper()
+	nsDir := filepath.Join(dir, "proc", pid, "ns")
+	if err := os.MkdirAll(nsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for typ, link := range links {
+		if err := os.Symlink(link, filepath.Join(nsDir, typ)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	writeNamespaces(t, dir, "42", map[string]string{
+		"pid":  "pid:[4026532291]",
+		"mnt":  "mnt:[4026532289]",
+		"net":  "net:[4026531840]",
+		"user": "user:[4026531837]",
+	})
+	writeNamespaces(t, dir, "43", map[string]string{
+		"pid": "net:[4026532291]",
+	})
+	rootFs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+
+	got, err := readNamespaces(afero.NewBasePathFs(rootFs, "/proc/42"))
+	if err != nil {
+		t.Fatalf("readNamespaces() = %v", err)
+	}
+	want := Namespaces{
+		"pid":  4026532291,
+		"mnt":  4026532289,
+		"net":  4026531840,
+		"user": 4026531837,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readNamespaces() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := readNamespaces(afero.NewBasePathFs(rootFs, "/proc/43")); err == nil {
+		t.Error("readNamespaces() = nil, want error for a link of another type")
+	}
+	if _, err := readNamespaces(afero.NewMemMapFs()); err == nil {
+		t.Error("readNamespaces() = nil, want error for a filesystem without links")
+	}
+}
+
+func TestParseNamespaceLink(t *testing.T) {
+	for _, link := range []string{"pid", "pid:[]", "pid:[12", "pid:[x]", "net:[12]"} {
+		if _, err := parseNamespaceLink("pid", link); err == nil {
+			t.Errorf("parseNamespaceLink(%q) = nil, want error", link)
+		}
+	}
+}
+
+func TestHasPIDNamespace(t *testing.T) {
+	host := Namespaces{"pid": 4026531836, "net": 4026531840}
+	for _, tc := range []struct {
+		name       string
+		pi         ProcessInfo
+		namespaced bool
+	}{
+		{
+			name:       "host",
+			pi:         ProcessInfo{globalPid: 42, localPid: 42, namespaces: Namespaces{"pid": 4026531836}, hostNamespaces: host},
+			namespaced: false,
+		},
+		{
+			name:       "container",
+			pi:         ProcessInfo{globalPid: 42, localPid: 1, namespaces: Namespaces{"pid": 4026532291}, hostNamespaces: host},
+			namespaced: true,
+		},
+		{
+			name:       "same pid in a container",
+			pi:         ProcessInfo{globalPid: 42, localPid: 42, namespaces: Namespaces{"pid": 4026532291}, hostNamespaces: host},
+			namespaced: true,
+		},
+		{
+			name:       "unknown namespaces",
+			pi:         ProcessInfo{globalPid: 42, localPid: 1},
+			namespaced: true,
+		},
+		{
+			name:       "unknown host namespaces",
+			pi:         ProcessInfo{globalPid: 42, localPid: 42, namespaces: Namespaces{"pid": 4026532291}},
+			namespaced: false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.pi.HasPIDNamespace(); got != tc.namespaced {
+				t.Errorf("HasPIDNamespace() = %v, want %v", got, tc.namespaced)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 
 	"github.com/spf13/afero"
@@ -14,6 +15,7 @@ import (
 	"github.com/noxiouz/gcoredumper/report"
 	"github.com/noxiouz/gcoredumper/utils/buildid"
 	"github.com/noxiouz/gcoredumper/utils/environ"
+	"github.com/noxiouz/gcoredumper/utils/procmaps"
 )
 
 type ProcessInfo struct {
@@ -33,6 +35,27 @@ type ProcessInfo struct {
 	env environ.Environ
 
 	utsname unix.Utsname
+
+	// memory layout at the time of the crash
+	mappings []procmaps.Mapping
+	modules  []Module
+	// nil unless the executable is built by Go
+	goBuildInfo *debug.BuildInfo
+
+	// state at the time of the crash, nil if it could not be read
+	status *Status
+	limits Limits
+	stat   *Stat
+
+	cgroups []Cgroup
+	// nil if the process does not run in a known container
+	container *Container
+	// namespaces of the process and of init
+	namespaces     Namespaces
+	hostNamespaces Namespaces
+
+	// procFs is rooted at /proc/<globalPid>
+	procFs afero.Fs
 }
 
 const (
@@ -53,6 +76,7 @@ func NewProcessInfo(ctx context.Context, globalPid int64, localPid int64, global
 	report.R(ctx).AddInt("tid.ns", localTid)
 
 	procFs := afero.NewBasePathFs(filesystem, fmt.Sprintf("/proc/%d", globalPid))
+	pi.procFs = procFs
 	cmdline, err := afero.ReadFile(procFs, "cmdline")
 	if err != nil {
 		return nil, err
@@ -69,8 +93,78 @@ func NewProcessInfo(ctx context.Context, globalPid int64, localPid int64, global
 	pi.binary = filepath.Base(pi.excutable)
 	report.R(ctx).AddString("binary", pi.binary)
 
+	// the build ID is not essential for the dump, binaries may be linked
+	// without it or -exe of a replay may be stripped
 	if err := extractElfInfo(procFs, report.R(ctx)); err != nil {
-		return nil, err
+		log.Printf("warning: no build id of %s: %v", pi.excutable, err)
+		report.R(ctx).AddError("binary.buildid.error", err)
+	}
+	// fails for non Go binaries
+	if info, err := readGoBuildInfo(procFs); err == nil {
+		pi.goBuildInfo = info
+		addGoBuildInfo(report.R(ctx), info)
+	}
+
+	// the memory layout is not essential for the dump
+	if mappings, err := readMappings(procFs); err != nil {
+		log.Printf("unable to read maps: %v", err)
+		report.R(ctx).AddError("maps.error", err)
+	} else {
+		pi.mappings = mappings
+		pi.modules = groupModules(mappings)
+		for i := range pi.modules {
+			module := &pi.modules[i]
+			if module.BuildID, err = readBuildID(procFs, module); err != nil {
+				log.Printf("unable to read build id of %s: %v", module.Path, err)
+			}
+		}
+		report.R(ctx).AddModules("modules", modulesRecord(pi.modules))
+	}
+
+	// the state is gone once the dump is done, but not essential for it
+	if pi.status, err = readStatus(procFs); err != nil {
+		log.Printf("unable to read status: %v", err)
+		report.R(ctx).AddError("status.error", err)
+	} else {
+		addStatus(report.R(ctx), pi.status)
+	}
+	if pi.limits, err = readLimits(procFs); err != nil {
+		log.Printf("unable to read limits: %v", err)
+		report.R(ctx).AddError("limits.error", err)
+	} else {
+		addLimits(report.R(ctx), pi.limits)
+	}
+	if pi.stat, err = readStat(procFs); err != nil {
+		log.Printf("unable to read stat: %v", err)
+		report.R(ctx).AddError("stat.error", err)
+	} else {
+		addStat(report.R(ctx), pi.stat)
+	}
+
+	if pi.cgroups, err = readCgroups(procFs); err != nil {
+		log.Printf("unable to read cgroup: %v", err)
+		report.R(ctx).AddError("cgroup.error", err)
+	} else {
+		for _, cgroup := range pi.cgroups {
+			report.R(ctx).AddString("cgroup", cgroup.String())
+		}
+		if pi.container = detectContainer(pi.cgroups); pi.container != nil {
+			report.R(ctx).AddString("container.runtime", pi.container.Runtime)
+			report.R(ctx).AddString("container.id", pi.container.ID)
+		}
+	}
+	if pi.namespaces, err = readNamespaces(procFs); err != nil {
+		log.Printf("unable to read namespaces: %v", err)
+		report.R(ctx).AddError("ns.error", err)
+	} else {
+		for _, typ := range namespaceTypes {
+			if inode, ok := pi.namespaces[typ]; ok {
+				report.R(ctx).AddInt("ns."+typ, int64(inode))
+			}
+		}
+	}
+	if pi.hostNamespaces, err = readNamespaces(afero.NewBasePathFs(filesystem, "/proc/1")); err != nil {
+		log.Printf("unable to read namespaces of init: %v", err)
 	}
 
 	if err := unix.Uname(&pi.utsname); err != nil {
@@ -89,14 +183,66 @@ func NewProcessInfo(ctx context.Context, globalPid int64, localPid int64, global
 	return pi, nil
 }
 
+// HasPIDNamespace reports whether the process runs in a PID namespace
+// other than the one of init. If namespaces are unknown, e.g. for
+// a replayed core, pids are compared instead, that misses namespaces
+// where the pid happens to be the same.
 func (p *ProcessInfo) HasPIDNamespace() bool {
-	return p.globalPid == p.localPid
+	if differs, known := p.namespaces.differs(p.hostNamespaces, "pid"); known {
+		return differs
+	}
+	return p.globalPid != p.localPid
+}
+
+// Cgroups returns the cgroups of the process, nil if they could not be read.
+func (p *ProcessInfo) Cgroups() []Cgroup {
+	return p.cgroups
+}
+
+// Container returns the container of the process, nil if there is none.
+func (p *ProcessInfo) Container() *Container {
+	return p.container
+}
+
+// Namespaces returns namespace inodes of the process.
+func (p *ProcessInfo) Namespaces() Namespaces {
+	return p.namespaces
 }
 
 func (p *ProcessInfo) IsBinaryDeleted() bool {
 	return p.executableDeleted
 }
 
+// Mappings returns the memory layout of the process ordered by address.
+func (p *ProcessInfo) Mappings() []procmaps.Mapping {
+	return p.mappings
+}
+
+// Modules returns the binary and shared objects mapped into the process.
+func (p *ProcessInfo) Modules() []Module {
+	return p.modules
+}
+
+// GoBuildInfo returns the build info of a Go executable, nil for others.
+func (p *ProcessInfo) GoBuildInfo() *debug.BuildInfo {
+	return p.goBuildInfo
+}
+
+// Status returns the snapshot of status, nil if it could not be read.
+func (p *ProcessInfo) Status() *Status {
+	return p.status
+}
+
+// Limits returns the resource limits, nil if they could not be read.
+func (p *ProcessInfo) Limits() Limits {
+	return p.limits
+}
+
+// Stat returns the snapshot of stat, nil if it could not be read.
+func (p *ProcessInfo) Stat() *Stat {
+	return p.stat
+}
+
 func (p *ProcessInfo) Env() environ.Environ {
 	return p.env
 }
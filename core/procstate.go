@@ -0,0 +1,286 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+// userHZ is the unit of times in stat, sysconf(_SC_CLK_TCK) is 100 on Linux
+const userHZ = 100
+
+// Unlimited is a value of Limit for RLIM_INFINITY
+const Unlimited = math.MaxUint64
+
+// Status is a snapshot of /proc/<pid>/status.
+type Status struct {
+	// Uid and Gid are real, effective, saved set and filesystem IDs
+	Uid     [4]uint32
+	Gid     [4]uint32
+	Threads int64
+	// VmRSS and VmPeak are in bytes
+	VmRSS  uint64
+	VmPeak uint64
+	// Seccomp mode: 0 disabled, 1 strict, 2 filter
+	Seccomp int64
+	// Capability sets
+	CapInh uint64
+	CapPrm uint64
+	CapEff uint64
+	CapBnd uint64
+	CapAmb uint64
+}
+
+// Limit is a resource limit, Unlimited if it is not set.
+type Limit struct {
+	Soft uint64
+	Hard uint64
+}
+
+// Limits are /proc/<pid>/limits keyed by the kernel description,
+// e.g. "Max core file size".
+type Limits map[string]Limit
+
+// Stat is a snapshot of /proc/<pid>/stat.
+type Stat struct {
+	State string
+	Ppid  int64
+	// Utime and Stime are CPU time spent in user and kernel mode
+	Utime time.Duration
+	Stime time.Duration
+	// StartTime is since boot
+	StartTime time.Duration
+}
+
+func readStatus(procFs afero.Fs) (*Status, error) {
+	f, err := procFs.Open("status")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseStatus(f)
+}
+
+func parseStatus(r io.Reader) (*Status, error) {
+	var (
+		status  Status
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch key {
+		case "Uid":
+			err = parseIDs(value, &status.Uid)
+		case "Gid":
+			err = parseIDs(value, &status.Gid)
+		case "Threads":
+			status.Threads, err = strconv.ParseInt(value, 10, 64)
+		case "VmRSS":
+			status.VmRSS, err = parseKB(value)
+		case "VmPeak":
+			status.VmPeak, err = parseKB(value)
+		case "Seccomp":
+			status.Seccomp, err = strconv.ParseInt(value, 10, 64)
+		case "CapInh":
+			status.CapInh, err = strconv.ParseUint(value, 16, 64)
+		case "CapPrm":
+			status.CapPrm, err = strconv.ParseUint(value, 16, 64)
+		case "CapEff":
+			status.CapEff, err = strconv.ParseUint(value, 16, 64)
+		case "CapBnd":
+			status.CapBnd, err = strconv.ParseUint(value, 16, 64)
+		case "CapAmb":
+			status.CapAmb, err = strconv.ParseUint(value, 16, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s: %w", key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// parseIDs parses the four tab separated IDs of Uid and Gid lines
+func parseIDs(value string, ids *[4]uint32) error {
+	fields := strings.Fields(value)
+	if len(fields) != len(ids) {
+		return fmt.Errorf("%d IDs, expected %d", len(fields), len(ids))
+	}
+	for i, field := range fields {
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return err
+		}
+		ids[i] = uint32(id)
+	}
+	return nil
+}
+
+// parseKB parses "<N> kB" into bytes
+func parseKB(value string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * 1024, nil
+}
+
+func readLimits(procFs afero.Fs) (Limits, error) {
+	f, err := procFs.Open("limits")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseLimits(f)
+}
+
+// limitNameWidth is the width of the name column, see proc_pid_limits
+const limitNameWidth = 25
+
+func parseLimits(r io.Reader) (Limits, error) {
+	limits := make(Limits)
+	scanner := bufio.NewScanner(r)
+	// skip the header
+	scanner.Scan()
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) <= limitNameWidth {
+			continue
+		}
+		name := strings.TrimSpace(line[:limitNameWidth])
+		fields := strings.Fields(line[limitNameWidth:])
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed limit %q", line)
+		}
+		soft, err := parseLimit(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed limit %q: %w", name, err)
+		}
+		hard, err := parseLimit(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed limit %q: %w", name, err)
+		}
+		limits[name] = Limit{Soft: soft, Hard: hard}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func parseLimit(value string) (uint64, error) {
+	if value == "unlimited" {
+		return Unlimited, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+func readStat(procFs afero.Fs) (*Stat, error) {
+	content, err := afero.ReadFile(procFs, "stat")
+	if err != nil {
+		return nil, err
+	}
+	return parseStat(string(content))
+}
+
+// parseStat parses fields of stat following comm, which may contain
+// spaces and parentheses, so fields are counted from the last ')'.
+func parseStat(content string) (*Stat, error) {
+	i := strings.LastIndexByte(content, ')')
+	if i < 0 {
+		return nil, fmt.Errorf("malformed stat %q", content)
+	}
+	// fields[0] is the 3rd field, state, see proc(5)
+	fields := strings.Fields(content[i+1:])
+	if len(fields) < 20 {
+		return nil, fmt.Errorf("malformed stat: %d fields", len(fields))
+	}
+	field := func(n int) (uint64, error) {
+		v, err := strconv.ParseUint(fields[n-3], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed stat field %d: %w", n, err)
+		}
+		return v, nil
+	}
+	ticks := func(n int) (time.Duration, error) {
+		v, err := field(n)
+		// divided first, ticks of years overflow otherwise
+		return time.Duration(v) * (time.Second / userHZ), err
+	}
+	stat := &Stat{State: fields[0]}
+	ppid, err := field(4)
+	if err != nil {
+		return nil, err
+	}
+	stat.Ppid = int64(ppid)
+	if stat.Utime, err = ticks(14); err != nil {
+		return nil, err
+	}
+	if stat.Stime, err = ticks(15); err != nil {
+		return nil, err
+	}
+	if stat.StartTime, err = ticks(22); err != nil {
+		return nil, err
+	}
+	return stat, nil
+}
+
+// reportedLimits maps limits to report keys
+var reportedLimits = []struct {
+	name string
+	key  string
+}{
+	{"Max core file size", "limits.core"},
+	{"Max address space", "limits.as"},
+	{"Max open files", "limits.nofile"},
+	{"Max stack size", "limits.stack"},
+}
+
+func addStatus(rep *report.Report, status *Status) {
+	rep.AddInt("status.uid", int64(status.Uid[0]))
+	rep.AddInt("status.euid", int64(status.Uid[1]))
+	rep.AddInt("status.gid", int64(status.Gid[0]))
+	rep.AddInt("status.egid", int64(status.Gid[1]))
+	rep.AddInt("status.threads", status.Threads)
+	rep.AddInt("status.vm_rss", int64(status.VmRSS))
+	rep.AddInt("status.vm_peak", int64(status.VmPeak))
+	rep.AddInt("status.seccomp", status.Seccomp)
+	rep.AddString("status.cap_prm", fmt.Sprintf("0x%x", status.CapPrm))
+	rep.AddString("status.cap_eff", fmt.Sprintf("0x%x", status.CapEff))
+	rep.AddString("status.cap_bnd", fmt.Sprintf("0x%x", status.CapBnd))
+}
+
+// addLimits reports soft and hard values, -1 stands for unlimited
+func addLimits(rep *report.Report, limits Limits) {
+	for _, l := range reportedLimits {
+		limit, ok := limits[l.name]
+		if !ok {
+			continue
+		}
+		rep.AddInt(l.key+".soft", int64(limit.Soft))
+		rep.AddInt(l.key+".hard", int64(limit.Hard))
+	}
+}
+
+func addStat(rep *report.Report, stat *Stat) {
+	rep.AddString("stat.state", stat.State)
+	rep.AddInt("stat.ppid", stat.Ppid)
+	rep.AddDuration("stat.utime", stat.Utime)
+	rep.AddDuration("stat.stime", stat.Stime)
+	rep.AddDuration("stat.start_time", stat.StartTime)
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+const testStatus = "Name:\tserver\n" +
+	"Umask:\t0022\n" +
+	"State:\tR (running)\n" +
+	"Tgid:\t4242\n" +
+	"PPid:\t1\n" +
+	"Uid:\t1000\t1001\t1002\t1003\n" +
+	"Gid:\t2000\t2001\t2002\t2003\n" +
+	"VmPeak:\t  204800 kB\n" +
+	"VmSize:\t  102400 kB\n" +
+	"VmRSS:\t    1776 kB\n" +
+	"Threads:\t12\n" +
+	"CapInh:\t0000000000000000\n" +
+	"CapPrm:\t0000000000000400\n" +
+	"CapEff:\t0000000000000400\n" +
+	"CapBnd:\t000001ffffffffff\n" +
+	"CapAmb:\t0000000000000000\n" +
+	"Seccomp:\t2\n" +
+	"Seccomp_filters:\t1\n"
+
+const testLimits = `Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max core file size        0                    unlimited            bytes
+Max open files            1024                 524288               files
+Max nice priority         0                    0
+Max realtime timeout      unlimited            unlimited            us
+`
+
+// comm with spaces and parentheses
+const testStat = "4242 (my (weird) app) R 1 4242 4242 0 -1 4194560 1234 0 0 0 " +
+	"250 130 0 0 20 0 12 0 987654 104857600 444 18446744073709551615 " +
+	"1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+
+func TestReadStatus(t *testing.T) {
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "status", []byte(testStatus), 0444); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readStatus(procFs)
+	if err != nil {
+		t.Fatalf("readStatus() = %v", err)
+	}
+	want := &Status{
+		Uid:     [4]uint32{1000, 1001, 1002, 1003},
+		Gid:     [4]uint32{2000, 2001, 2002, 2003},
+		Threads: 12,
+		VmRSS:   1776 * 1024,
+		VmPeak:  204800 * 1024,
+		Seccomp: 2,
+		CapPrm:  0x400,
+		CapEff:  0x400,
+		CapBnd:  0x1ffffffffff,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readStatus() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseStatusMalformed(t *testing.T) {
+	for _, status := range []string{
+		"Uid:\t1000\t1000\n",
+		"Threads:\tmany\n",
+		"VmRSS:\t12 MB\n",
+		"CapEff:\tzzzz\n",
+	} {
+		procFs := afero.NewMemMapFs()
+		if err := afero.WriteFile(procFs, "status", []byte(status), 0444); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readStatus(procFs); err == nil {
+			t.Errorf("readStatus(%q) = nil, want error", status)
+		}
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "limits", []byte(testLimits), 0444); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readLimits(procFs)
+	if err != nil {
+		t.Fatalf("readLimits() = %v", err)
+	}
+	want := Limits{
+		"Max cpu time":         {Soft: Unlimited, Hard: Unlimited},
+		"Max core file size":   {Soft: 0, Hard: Unlimited},
+		"Max open files":       {Soft: 1024, Hard: 524288},
+		"Max nice priority":    {Soft: 0, Hard: 0},
+		"Max realtime timeout": {Soft: Unlimited, Hard: Unlimited},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readLimits() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadStat(t *testing.T) {
+	procFs := afero.NewMemMapFs()
+	if err := afero.WriteFile(procFs, "stat", []byte(testStat), 0444); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readStat(procFs)
+	if err != nil {
+		t.Fatalf("readStat() = %v", err)
+	}
+	want := &Stat{
+		State:     "R",
+		Ppid:      1,
+		Utime:     2500 * time.Millisecond,
+		Stime:     1300 * time.Millisecond,
+		StartTime: 9876540 * time.Millisecond,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readStat() mismatch (-want +got):\n%s", diff)
+	}
+
+	// 10 years of uptime, overflows if multiplied by a second first
+	const bigStat = "4242 (server) S 1 4242 4242 0 -1 4194560 1234 0 0 0 " +
+		"31536000000 130 0 0 20 0 12 0 31536000000 104857600 444"
+	big, err := parseStat(bigStat)
+	if err != nil {
+		t.Fatalf("parseStat() = %v", err)
+	}
+	if want := 10 * 365 * 24 * time.Hour; big.Utime != want || big.StartTime != want {
+		t.Errorf("Utime, StartTime = %v, %v, want %v", big.Utime, big.StartTime, want)
+	}
+
+	for _, stat := range []string{
+		"4242 server R 1",
+		"4242 (server) R 1 2 3",
+		"4242 (server) R x 4242 4242 0 -1 4194560 1234 0 0 0 250 130 0 0 20 0 12 0 987654",
+	} {
+		if _, err := parseStat(stat); err == nil {
+			t.Errorf("parseStat(%q) = nil, want error", stat)
+		}
+	}
+}
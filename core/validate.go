@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+// maxSignal is the last real-time signal on Linux
+const maxSignal = 64
+
+var errMissing = errors.New("missing")
+
+// InputError describes a missing or invalid core_pattern argument.
+type InputError struct {
+	// Key is a report key suffix the argument is stored under, e.g. pid.global
+	Key string
+	// Specifier is the core_pattern specifier, e.g. %P
+	Specifier string
+	Err       error
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Key, e.Specifier, e.Err)
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError aggregates all problems found in SystemInput.
+type ValidationError struct {
+	Errors []*InputError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid core_pattern arguments: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks that required arguments have been passed by the kernel
+// and cross-checks %P and %I against /proc/<pid>/task.
+// Every problem is reported as a distinct input.error.<key> record.
+func (si *SystemInput) Validate(ctx context.Context) error {
+	filesystem := si.filesystem()
+	var errs []*InputError
+	check := func(key, specifier string, err error) {
+		if err == nil {
+			return
+		}
+		inputErr := &InputError{Key: key, Specifier: specifier, Err: err}
+		report.R(ctx).AddError("input.error."+key, inputErr)
+		errs = append(errs, inputErr)
+	}
+	positive := func(v int64) error {
+		if v <= 0 {
+			return errMissing
+		}
+		return nil
+	}
+
+	check("pid.ns", "%p", positive(si.NsPid))
+	check("tid.ns", "%i", positive(si.NsTid))
+	if err := positive(si.InitialPid); err != nil {
+		check("pid.global", "%P", err)
+	} else {
+		check("pid.global", "%P", pathExists(filesystem, procPath(si.InitialPid)))
+		if err := positive(si.InitialTid); err != nil {
+			check("tid.global", "%I", err)
+		} else {
+			check("tid.global", "%I", pathExists(filesystem, procPath(si.InitialPid, "task", strconv.FormatInt(si.InitialTid, 10))))
+		}
+	}
+	if si.Executable == "" {
+		check("executable", "%E", errMissing)
+	}
+	if si.Signal <= 0 || int(si.Signal) > maxSignal {
+		check("signal", "%s", fmt.Errorf("%d is not a valid signal number", si.Signal))
+	}
+	switch si.PrGetDumpable {
+	case DUMPABLE_DEFAULT, DUMPABLE_DEBUG, DUMPABLE_SUIDSAFE:
+	default:
+		check("dumpable", "%d", fmt.Errorf("unknown dump mode %d", si.PrGetDumpable))
+	}
+	if si.DumpTime.Unix() <= 0 {
+		check("timestamp", "%t", errMissing)
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func procPath(pid int64, elem ...string) string {
+	return path.Join(append([]string{"/proc", strconv.FormatInt(pid, 10)}, elem...)...)
+}
+
+func pathExists(filesystem afero.Fs, p string) error {
+	exists, err := afero.Exists(filesystem, p)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s does not exist", p)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+func TestValidate(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := fs.MkdirAll("/proc/100/task/101", 0755); err != nil {
+		t.Fatal(err)
+	}
+	valid := SystemInput{
+		Executable:    "!usr!bin!foo",
+		InitialPid:    100,
+		NsPid:         1,
+		InitialTid:    101,
+		NsTid:         2,
+		PrGetDumpable: DUMPABLE_DEBUG,
+		DumpTime:      time.Unix(1650000000, 0),
+		Signal:        syscall.SIGSEGV,
+		Filesystem:    fs,
+	}
+	for _, tc := range []struct {
+		name   string
+		modify func(si *SystemInput)
+		want   []string
+	}{
+		{
+			name:   "Valid",
+			modify: func(si *SystemInput) {},
+		},
+		{
+			name: "Missing",
+			modify: func(si *SystemInput) {
+				*si = SystemInput{Filesystem: fs}
+			},
+			want: []string{"pid.ns", "tid.ns", "pid.global", "executable", "signal", "timestamp"},
+		},
+		{
+			name: "NoSuchProcess",
+			modify: func(si *SystemInput) {
+				si.InitialPid = 200
+			},
+			want: []string{"pid.global", "tid.global"},
+		},
+		{
+			name: "TidFromAnotherProcess",
+			modify: func(si *SystemInput) {
+				si.InitialTid = 102
+			},
+			want: []string{"tid.global"},
+		},
+		{
+			name: "InvalidSignalAndDumpable",
+			modify: func(si *SystemInput) {
+				si.Signal = 65
+				si.PrGetDumpable = 3
+			},
+			want: []string{"signal", "dumpable"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			si := valid
+			tc.modify(&si)
+			err := si.Validate(context.Background())
+			var got []string
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				for _, e := range verr.Errors {
+					got = append(got, e.Key)
+				}
+			} else if err != nil {
+				t.Fatalf("Validate() returned unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Validate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
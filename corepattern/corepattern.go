@@ -0,0 +1,154 @@
+package corepattern
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	DefaultSysctlRoot = "/proc/sys"
+	DefaultStateFile  = "/var/lib/gcoredumper/core_pattern.state"
+	DefaultPipeLimit  = 16
+
+	corePatternFile   = "kernel/core_pattern"
+	corePipeLimitFile = "kernel/core_pipe_limit"
+
+	// CORENAME_MAX_SIZE in fs/coredump.c includes the trailing NUL.
+	maxPatternLen = 127
+)
+
+// handlerArgs are core_pattern specifiers in the order main.go expects them.
+var handlerArgs = []string{
+	"-P", "%P", // PID in initial namespace
+	"-p", "%p", // PID in process namespace
+	"-I", "%I", // TID in initial namespace
+	"-i", "%i", // TID in process namespace
+	"-E", "%E", // pathname of executable
+	"-s", "%s", // signal number
+	"-d", "%d", // dump mode
+	"-t", "%t", // time of dump
+}
+
+// ErrNotInstalled is returned by Uninstall when there is no saved state.
+var ErrNotInstalled = errors.New("gcoredumper is not installed: no saved state")
+
+// Pattern builds a pipe core_pattern that invokes executable with
+// the arguments expected by gcoredumper followed by extraArgs.
+func Pattern(executable string, extraArgs ...string) (string, error) {
+	if !filepath.IsAbs(executable) {
+		return "", fmt.Errorf("executable path must be absolute: %s", executable)
+	}
+	args := append([]string{"|" + executable}, handlerArgs...)
+	args = append(args, extraArgs...)
+	pattern := strings.Join(args, " ")
+	if len(pattern) > maxPatternLen {
+		return "", fmt.Errorf("core_pattern is %d bytes long, kernel limit is %d: %s", len(pattern), maxPatternLen, pattern)
+	}
+	return pattern, nil
+}
+
+// state keeps kernel settings as they were before Install.
+type state struct {
+	CorePattern   string `json:"core_pattern"`
+	CorePipeLimit string `json:"core_pipe_limit"`
+}
+
+// Installer manages kernel.core_pattern and kernel.core_pipe_limit
+// under SysctlRoot and remembers their previous values in StateFile.
+type Installer struct {
+	Fs         afero.Fs
+	SysctlRoot string
+	StateFile  string
+}
+
+func New(fs afero.Fs) *Installer {
+	return &Installer{
+		Fs:         fs,
+		SysctlRoot: DefaultSysctlRoot,
+		StateFile:  DefaultStateFile,
+	}
+}
+
+// Install saves the current settings unless they were saved by a previous
+// Install and writes pattern and pipeLimit.
+func (i *Installer) Install(pattern string, pipeLimit int) error {
+	saved, err := afero.Exists(i.Fs, i.StateFile)
+	if err != nil {
+		return err
+	}
+	if !saved {
+		var prev state
+		if prev.CorePattern, err = i.readSysctl(corePatternFile); err != nil {
+			return err
+		}
+		if prev.CorePipeLimit, err = i.readSysctl(corePipeLimitFile); err != nil {
+			return err
+		}
+		if err := i.saveState(&prev); err != nil {
+			return err
+		}
+	}
+
+	if err := i.writeSysctl(corePipeLimitFile, strconv.Itoa(pipeLimit)); err != nil {
+		return err
+	}
+	return i.writeSysctl(corePatternFile, pattern)
+}
+
+// Uninstall restores settings saved by Install and removes the state file.
+func (i *Installer) Uninstall() error {
+	body, err := afero.ReadFile(i.Fs, i.StateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotInstalled
+		}
+		return err
+	}
+	var prev state
+	if err := json.Unmarshal(body, &prev); err != nil {
+		return fmt.Errorf("malformed state file %s: %w", i.StateFile, err)
+	}
+
+	if err := i.writeSysctl(corePatternFile, prev.CorePattern); err != nil {
+		return err
+	}
+	if err := i.writeSysctl(corePipeLimitFile, prev.CorePipeLimit); err != nil {
+		return err
+	}
+	return i.Fs.Remove(i.StateFile)
+}
+
+// Current returns the installed core_pattern.
+func (i *Installer) Current() (string, error) {
+	return i.readSysctl(corePatternFile)
+}
+
+func (i *Installer) saveState(s *state) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := i.Fs.MkdirAll(filepath.Dir(i.StateFile), 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(i.Fs, i.StateFile, body, 0644)
+}
+
+func (i *Installer) readSysctl(name string) (string, error) {
+	body, err := afero.ReadFile(i.Fs, filepath.Join(i.SysctlRoot, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(body), "\n"), nil
+}
+
+func (i *Installer) writeSysctl(name string, value string) error {
+	return afero.WriteFile(i.Fs, filepath.Join(i.SysctlRoot, name), []byte(value+"\n"), 0644)
+}
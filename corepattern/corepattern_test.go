@@ -0,0 +1,87 @@
+package corepattern
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+func newFakeSysctl(t *testing.T, pattern, pipeLimit string) (afero.Fs, *Installer) {
+	t.Helper()
+	fs := afero.NewMemMapFs()
+	i := New(fs)
+	i.SysctlRoot = "/fakeproc/sys"
+	i.StateFile = "/state/core_pattern.state"
+	if err := i.writeSysctl(corePatternFile, pattern); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.writeSysctl(corePipeLimitFile, pipeLimit); err != nil {
+		t.Fatal(err)
+	}
+	return fs, i
+}
+
+func TestPattern(t *testing.T) {
+	got, err := Pattern("/usr/bin/gcoredumper", "-cfg", "file:/etc/gcoredumper/config.prototxt")
+	if err != nil {
+		t.Fatalf("Pattern() returned unexpected error %v", err)
+	}
+	want := "|/usr/bin/gcoredumper -P %P -p %p -I %I -i %i -E %E -s %s -d %d -t %t -cfg file:/etc/gcoredumper/config.prototxt"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Pattern() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, exe := range []string{"gcoredumper", "/" + strings.Repeat("x", maxPatternLen)} {
+		if _, err := Pattern(exe); err == nil {
+			t.Errorf("Pattern(%q) expected to return an error, but got nil", exe)
+		}
+	}
+}
+
+func TestInstallUninstall(t *testing.T) {
+	fs, i := newFakeSysctl(t, "core", "0")
+
+	for _, pattern := range []string{"|/usr/bin/gcoredumper -P %P", "|/usr/local/bin/gcoredumper -P %P"} {
+		if err := i.Install(pattern, 4); err != nil {
+			t.Fatalf("Install() returned unexpected error %v", err)
+		}
+		got, err := i.Current()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != pattern {
+			t.Errorf("Current() = %q, want %q", got, pattern)
+		}
+		limit, err := afero.ReadFile(fs, "/fakeproc/sys/kernel/core_pipe_limit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(limit) != "4\n" {
+			t.Errorf("core_pipe_limit = %q, want %q", limit, "4\n")
+		}
+	}
+
+	// Reinstall must not overwrite the original settings
+	if err := i.Uninstall(); err != nil {
+		t.Fatalf("Uninstall() returned unexpected error %v", err)
+	}
+	for file, want := range map[string]string{
+		"/fakeproc/sys/kernel/core_pattern":    "core\n",
+		"/fakeproc/sys/kernel/core_pipe_limit": "0\n",
+	} {
+		got, err := afero.ReadFile(fs, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+
+	if err := i.Uninstall(); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("second Uninstall() = %v, want %v", err, ErrNotInstalled)
+	}
+}
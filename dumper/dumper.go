@@ -44,21 +44,66 @@ func (d *Dumper) Dump(ctx context.Context, r io.Reader, filepath string, config
 		filepath = filepath + suffix
 	}
 
-	file, err := d.fs.Create(path.Join(filepath))
-	if err != nil {
-		return "", err
+	if err := d.removeStaleTempFiles(ctx, directory); err != nil {
+		log.Printf("unable to remove stale temporary files: %v", err)
 	}
-	defer file.Close()
 
-	compressor, err := newCompressor(config, file)
+	// Write to a hidden temporary file and rename it on success,
+	// so nobody sees a partially written corefile.
+	tmpFilepath := tempFilepath(filepath)
+	file, err := d.fs.Create(tmpFilepath)
 	if err != nil {
 		return "", err
 	}
-	defer compressor.Close()
 
 	log.Printf("a coredump will be stored to %s", filepath)
 	log.Printf("a coredumper will be compressed with %s", config.Compression)
 
+	coreSize, err := d.writeCore(ctx, file, r, config)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.fs.Rename(tmpFilepath, filepath)
+	}
+	if err != nil {
+		d.fs.Remove(tmpFilepath)
+	} else if syncErr := d.syncDir(directory); syncErr != nil {
+		// the corefile is in place, it may only not survive a power loss
+		log.Printf("unable to sync %s: %v", directory, syncErr)
+		reporter.AddError("core.sync.error", syncErr)
+	}
+
+	if err == nil { // if NO error
+		reporter.AddInt("core.size", coreSize)
+		reporter.AddString("core.filepath", filepath)
+		reporter.AddString("core.compression", config.GetCompression().String())
+		reporter.AddDuration("core.dumpingduration", time.Now().Sub(dumpStarted))
+	}
+	reporter.AddError("core.error", err)
+	return filepath, err
+}
+
+// syncDir flushes directory entries, so a rename is not lost on a crash.
+func (d *Dumper) syncDir(directory string) error {
+	dir, err := d.fs.Open(directory)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeCore compresses r into file and flushes it to disk.
+func (d *Dumper) writeCore(ctx context.Context, file afero.File, r io.Reader, config *configuration.Config_DumperConfig) (int64, error) {
+	compressor, err := newCompressor(config, file)
+	if err != nil {
+		return 0, err
+	}
+
 	wr := xioutil.NewCancellableWriter(ctx, compressor)
 	if osFile, ok := file.(*os.File); ok {
 		diskUsageFn := func(p []byte) error {
@@ -77,17 +122,13 @@ func (d *Dumper) Dump(ctx context.Context, r io.Reader, filepath string, config
 		wr = xioutil.NewWhileWriter(diskUsageFn, wr)
 	}
 	coreSize, err := io.CopyBuffer(wr, r, nil)
-	if err != nil {
-		os.Remove(file.Name())
+	if closeErr := compressor.Close(); err == nil {
+		err = closeErr
 	}
-
-	if err == nil { // if NO error
-		reporter.AddInt("core.size", coreSize)
-		reporter.AddString("core.filepath", filepath)
-		reporter.AddDuration("core.dumpingduration", time.Now().Sub(dumpStarted))
+	if err != nil {
+		return 0, err
 	}
-	reporter.AddError("core.error", err)
-	return filepath, err
+	return coreSize, file.Sync()
 }
 
 func newCompressor(cfg *configuration.Config_DumperConfig, wr io.Writer) (io.WriteCloser, error) {
@@ -3,12 +3,15 @@ package dumper
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"path"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/klauspost/compress/snappy"
 	"github.com/klauspost/compress/zstd"
 	"github.com/noxiouz/gcoredumper/configuration"
+	"github.com/noxiouz/gcoredumper/report"
 	"github.com/spf13/afero"
 )
 
@@ -99,3 +102,130 @@ func TestDumpWithCompression(t *testing.T) {
 		})
 	}
 }
+
+func TestDumpLeavesNoTempFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := fs.MkdirAll("/cores", 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := New(fs)
+	if _, err := d.Dump(ctx, bytes.NewBufferString("content"), "/cores/corefile1", &configuration.Config_DumperConfig{
+		Compression: configuration.Config_DumperConfig_PLANE,
+	}); err == nil {
+		t.Fatalf("Dump() expected to return an error, but got nil")
+	}
+	infos, err := afero.ReadDir(fs, "/cores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		t.Errorf("unexpected file %s left after failed Dump()", info.Name())
+	}
+}
+
+func TestDumpRemovesStaleTempFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	alive := tempFilepath("/cores/alive")
+	for _, file := range []string{
+		alive,
+		"/cores/.stale.2147483647.tmp", // pid is above pid_max
+		"/cores/unrelated.2147483647.tmp",
+	} {
+		if err := afero.WriteFile(fs, file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := New(fs)
+	if _, err := d.Dump(context.Background(), bytes.NewBufferString("content"), "/cores/corefile1", &configuration.Config_DumperConfig{
+		Compression: configuration.Config_DumperConfig_PLANE,
+	}); err != nil {
+		t.Fatalf("Dump returned unexpected error %v", err)
+	}
+	var got []string
+	infos, err := afero.ReadDir(fs, "/cores")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		got = append(got, info.Name())
+	}
+	want := []string{path.Base(alive), "corefile1", "unrelated.2147483647.tmp"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadDir() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// syncRecordingFs records names of files synced after Open
+type syncRecordingFs struct {
+	afero.Fs
+	synced []string
+}
+
+func (fs *syncRecordingFs) Open(name string) (afero.File, error) {
+	f, err := fs.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &syncRecordingFile{File: f, fs: fs}, nil
+}
+
+type syncRecordingFile struct {
+	afero.File
+	fs *syncRecordingFs
+}
+
+func (f *syncRecordingFile) Sync() error {
+	f.fs.synced = append(f.fs.synced, f.Name())
+	return f.File.Sync()
+}
+
+func TestDumpSyncsDirectory(t *testing.T) {
+	fs := &syncRecordingFs{Fs: afero.NewMemMapFs()}
+	if err := fs.MkdirAll("/cores", 0755); err != nil {
+		t.Fatal(err)
+	}
+	d := New(fs)
+	if _, err := d.Dump(context.Background(), bytes.NewBufferString("content"), "/cores/corefile1", &configuration.Config_DumperConfig{
+		Compression: configuration.Config_DumperConfig_PLANE,
+	}); err != nil {
+		t.Fatalf("Dump returned unexpected error %v", err)
+	}
+	// the rename is flushed
+	if diff := cmp.Diff([]string{"/cores"}, fs.synced); diff != "" {
+		t.Errorf("synced mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriteSidecar(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	ctx := report.WithReport(context.Background(), report.New())
+	report.R(ctx).AddInt("pid.global", 100)
+	d := New(fs)
+	sidecar, err := d.WriteSidecar(ctx, "/corefile1")
+	if err != nil {
+		t.Fatalf("WriteSidecar returned unexpected error %v", err)
+	}
+	body, err := afero.ReadFile(fs, sidecar)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) returned an error %v", sidecar, err)
+	}
+	// the schema of JSONSink, numbers are not quoted
+	var got map[string]interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("sidecar is not a valid JSON %v: %s", err, body)
+	}
+	if got["report.uuid"] != report.R(ctx).ID() {
+		t.Errorf("report.uuid = %v, want %s", got["report.uuid"], report.R(ctx).ID())
+	}
+	delete(got, "report.uuid")
+	delete(got, "report.timestamp")
+	want := map[string]interface{}{
+		"pid.global":   float64(100),
+		"core.sidecar": "/corefile1.json",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("sidecar mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,123 @@
+package dumper
+
+import (
+	"context"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+// <binary>.<pid>.<tid>[.zstd|.snappy], see ProcessInfo.CorefileName
+var corefileRe = regexp.MustCompile(`^([^.].*)\.\d+\.\d+(\.zstd|\.snappy)?$`)
+
+// Corefile describes a corefile found in a corefiles directory.
+type Corefile struct {
+	Path    string
+	Binary  string
+	Size    int64
+	ModTime time.Time
+}
+
+// Retention evicts corefiles which exceed configured limits.
+type Retention struct {
+	fs  afero.Fs
+	now func() time.Time
+}
+
+func NewRetention(fs afero.Fs) *Retention {
+	return &Retention{
+		fs:  fs,
+		now: time.Now,
+	}
+}
+
+// ListCorefiles returns corefiles in directory, the newest first.
+func (r *Retention) ListCorefiles(directory string) ([]Corefile, error) {
+	infos, err := afero.ReadDir(r.fs, directory)
+	if err != nil {
+		return nil, err
+	}
+	var corefiles []Corefile
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		m := corefileRe.FindStringSubmatch(info.Name())
+		if m == nil {
+			continue
+		}
+		corefiles = append(corefiles, Corefile{
+			Path:    path.Join(directory, info.Name()),
+			Binary:  m[1],
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.SliceStable(corefiles, func(i, j int) bool {
+		return corefiles[i].ModTime.After(corefiles[j].ModTime)
+	})
+	return corefiles, nil
+}
+
+// Enforce removes corefiles from directory exceeding limits of cfg,
+// the oldest first, and returns the evicted ones. Once max_total_bytes
+// is exceeded, the corefile and all older ones are evicted. keep is
+// a path never evicted, e.g. the corefile just written, it still counts
+// against the limits.
+func (r *Retention) Enforce(ctx context.Context, directory string, cfg *configuration.Config_DumperConfig_Retention, keep string) ([]Corefile, error) {
+	corefiles, err := r.ListCorefiles(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		evicted   []Corefile
+		kept      uint32
+		keptBytes uint64
+		// max_total_bytes has been exceeded by a newer corefile
+		overBudget bool
+		perBinary  = make(map[string]uint32)
+		maxAge     = time.Duration(cfg.GetMaxAgeSec()) * time.Second
+		now        = r.now()
+	)
+	for _, corefile := range corefiles {
+		if cfg.GetMaxTotalBytes() > 0 && keptBytes+uint64(corefile.Size) > cfg.GetMaxTotalBytes() {
+			overBudget = true
+		}
+		evict := overBudget ||
+			maxAge > 0 && now.Sub(corefile.ModTime) > maxAge ||
+			cfg.GetKeepLastPerBinary() > 0 && perBinary[corefile.Binary] >= cfg.GetKeepLastPerBinary() ||
+			cfg.GetKeepLast() > 0 && kept >= cfg.GetKeepLast()
+		if evict && corefile.Path != keep {
+			evicted = append(evicted, corefile)
+			continue
+		}
+		kept++
+		keptBytes += uint64(corefile.Size)
+		perBinary[corefile.Binary]++
+	}
+
+	reporter := report.R(ctx)
+	var evictedBytes int64
+	// the oldest first
+	for i := len(evicted) - 1; i >= 0; i-- {
+		if err := r.fs.Remove(evicted[i].Path); err != nil && !os.IsNotExist(err) {
+			return evicted[i+1:], err
+		}
+		if err := r.fs.Remove(evicted[i].Path + SidecarSuffix); err != nil && !os.IsNotExist(err) {
+			return evicted[i:], err
+		}
+		evictedBytes += evicted[i].Size
+		reporter.AddString("retention.evicted", evicted[i].Path)
+	}
+	reporter.AddInt("retention.evicted.count", int64(len(evicted)))
+	reporter.AddInt("retention.evicted.bytes", evictedBytes)
+	return evicted, nil
+}
@@ -0,0 +1,109 @@
+package dumper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+func TestRetentionEnforce(t *testing.T) {
+	now := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	// the newest first
+	files := []struct {
+		name string
+		size int
+	}{
+		{"foo.10.10.zstd", 10},
+		{"bar.9.9", 20},
+		{"foo.8.8", 30},
+		{"foo.7.7.snappy", 40},
+		{"bar.6.6", 5},
+	}
+	for _, tc := range []struct {
+		name string
+		cfg  *configuration.Config_DumperConfig_Retention
+		keep string
+		want []string
+	}{
+		{
+			name: "NoLimits",
+			cfg:  &configuration.Config_DumperConfig_Retention{},
+		},
+		{
+			name: "KeepLast",
+			cfg:  &configuration.Config_DumperConfig_Retention{KeepLast: 3},
+			want: []string{"/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+		{
+			name: "KeepLastPerBinary",
+			cfg:  &configuration.Config_DumperConfig_Retention{KeepLastPerBinary: 1},
+			want: []string{"/cores/foo.8.8", "/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+		{
+			name: "MaxTotalBytes",
+			cfg:  &configuration.Config_DumperConfig_Retention{MaxTotalBytes: 60},
+			want: []string{"/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+		{
+			// bar.6.6 would fit, but it is older than evicted foo.7.7.snappy
+			name: "MaxTotalBytesOldestFirst",
+			cfg:  &configuration.Config_DumperConfig_Retention{MaxTotalBytes: 65},
+			want: []string{"/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+		{
+			name: "KeepNewCorefile",
+			cfg:  &configuration.Config_DumperConfig_Retention{MaxTotalBytes: 5},
+			keep: "/cores/foo.10.10.zstd",
+			want: []string{"/cores/bar.9.9", "/cores/foo.8.8", "/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+		{
+			name: "MaxAge",
+			cfg:  &configuration.Config_DumperConfig_Retention{MaxAgeSec: 3 * 3600},
+			want: []string{"/cores/foo.7.7.snappy", "/cores/bar.6.6"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for i, file := range files {
+				name := "/cores/" + file.name
+				if err := afero.WriteFile(fs, name, make([]byte, file.size), 0644); err != nil {
+					t.Fatal(err)
+				}
+				mtime := now.Add(-time.Duration(i) * time.Hour)
+				if err := fs.Chtimes(name, mtime, mtime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, name := range []string{"/cores/.foo.11.11.1234.tmp", "/cores/notes.txt"} {
+				if err := afero.WriteFile(fs, name, make([]byte, 100), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := NewRetention(fs)
+			r.now = func() time.Time { return now.Add(time.Minute) }
+			evicted, err := r.Enforce(context.Background(), "/cores", tc.cfg, tc.keep)
+			if err != nil {
+				t.Fatalf("Enforce() returned unexpected error %v", err)
+			}
+			var got []string
+			for _, corefile := range evicted {
+				got = append(got, corefile.Path)
+				if exists, _ := afero.Exists(fs, corefile.Path); exists {
+					t.Errorf("%s has not been removed", corefile.Path)
+				}
+			}
+			if exists, _ := afero.Exists(fs, tc.keep); tc.keep != "" && !exists {
+				t.Errorf("%s has been removed", tc.keep)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Enforce() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package dumper
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"path"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+// SidecarSuffix is appended to a corefile path to get its metadata sidecar.
+const SidecarSuffix = ".json"
+
+// WriteSidecar writes all records collected so far to <corefile>.json
+// as a single JSONSink line.
+// The sidecar is renamed into place after the corefile,
+// so its presence means the corefile is complete.
+func (d *Dumper) WriteSidecar(ctx context.Context, corefile string) (string, error) {
+	reporter := report.R(ctx)
+	sidecar := corefile + SidecarSuffix
+	reporter.AddString("core.sidecar", sidecar)
+
+	body, err := json.Marshal(reporter)
+	if err != nil {
+		return "", err
+	}
+
+	tmpFilepath := tempFilepath(sidecar)
+	file, err := d.fs.Create(tmpFilepath)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(body)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.fs.Rename(tmpFilepath, sidecar)
+	}
+	if err != nil {
+		d.fs.Remove(tmpFilepath)
+		return "", err
+	}
+	if err := d.syncDir(path.Dir(sidecar)); err != nil {
+		log.Printf("unable to sync %s: %v", path.Dir(sidecar), err)
+	}
+	return sidecar, nil
+}
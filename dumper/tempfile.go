@@ -0,0 +1,56 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/spf13/afero"
+	"golang.org/x/sys/unix"
+
+	"github.com/noxiouz/gcoredumper/report"
+)
+
+const tempSuffix = ".tmp"
+
+// .<corefile>.<pid of gcoredumper>.tmp
+var tempFileRe = regexp.MustCompile(`^\..+\.(\d+)\` + tempSuffix + `$`)
+
+// tempFilepath returns a hidden name in the same directory as filepath,
+// so the final rename does not cross filesystems.
+func tempFilepath(filepath string) string {
+	dir, name := path.Split(filepath)
+	return path.Join(dir, fmt.Sprintf(".%s.%d%s", name, os.Getpid(), tempSuffix))
+}
+
+// removeStaleTempFiles removes temporary files left by gcoredumper
+// instances which are not running anymore.
+func (d *Dumper) removeStaleTempFiles(ctx context.Context, directory string) error {
+	infos, err := afero.ReadDir(d.fs, directory)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		m := tempFileRe.FindStringSubmatch(info.Name())
+		if m == nil || info.IsDir() {
+			continue
+		}
+		pid, err := strconv.Atoi(m[1])
+		if err != nil || isProcessAlive(pid) {
+			continue
+		}
+		stale := path.Join(directory, info.Name())
+		if err := d.fs.Remove(stale); err != nil {
+			return err
+		}
+		report.R(ctx).AddString("core.stale.removed", stale)
+	}
+	return nil
+}
+
+func isProcessAlive(pid int) bool {
+	return unix.Kill(pid, 0) != unix.ESRCH
+}
@@ -3,14 +3,14 @@ module github.com/noxiouz/gcoredumper
 go 1.18
 
 require (
-	github.com/cilium/ebpf v0.8.1
+	github.com/cilium/ebpf v0.10.0
 	github.com/golang/protobuf v1.5.0
-	github.com/google/go-cmp v0.5.6
+	github.com/google/go-cmp v0.5.9
 	github.com/google/uuid v1.3.0
 	github.com/klauspost/compress v1.15.1
 	github.com/spf13/afero v1.8.2
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
-	golang.org/x/sys v0.0.0-20220405052023-b1e9470b6e64
+	golang.org/x/sys v0.2.0
 	google.golang.org/protobuf v1.28.0
 )
 
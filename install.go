@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/corepattern"
+)
+
+func newInstaller(fset *flag.FlagSet) *corepattern.Installer {
+	i := corepattern.New(afero.NewOsFs())
+	fset.StringVar(&i.SysctlRoot, "sysctl-root", corepattern.DefaultSysctlRoot, "root of sysctl tree")
+	fset.StringVar(&i.StateFile, "state", corepattern.DefaultStateFile, "file to keep the previous core_pattern in")
+	return i
+}
+
+func installCmd(args []string) error {
+	fset := flag.NewFlagSet("install", flag.ExitOnError)
+	installer := newInstaller(fset)
+	pipeLimit := fset.Int("pipe-limit", corepattern.DefaultPipeLimit, "kernel.core_pipe_limit value")
+	cfg := fset.String("cfg", "", "configurator URI passed to the handler")
+	fset.Parse(args)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	var extraArgs []string
+	if *cfg != "" {
+		extraArgs = append(extraArgs, "-cfg", *cfg)
+	}
+	pattern, err := corepattern.Pattern(exe, extraArgs...)
+	if err != nil {
+		return err
+	}
+	if err := installer.Install(pattern, *pipeLimit); err != nil {
+		return err
+	}
+	fmt.Printf("kernel.core_pattern = %s\n", pattern)
+	return nil
+}
+
+func uninstallCmd(args []string) error {
+	fset := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	installer := newInstaller(fset)
+	fset.Parse(args)
+
+	if err := installer.Uninstall(); err != nil {
+		return err
+	}
+	pattern, err := installer.Current()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("kernel.core_pattern = %s\n", pattern)
+	return nil
+}
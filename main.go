@@ -10,8 +10,7 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
-
+	"github.com/noxiouz/gcoredumper/configuration"
 	"github.com/noxiouz/gcoredumper/configuration/configurator"
 	_ "github.com/noxiouz/gcoredumper/configuration/configurator/localfile"
 	"github.com/noxiouz/gcoredumper/core"
@@ -27,47 +26,47 @@ var (
 	signalNum      = flag.Int("s", 0, "signal num %s")
 	dumpable       = flag.Int("d", 0, "dumpable")
 	timestampInSec = flag.Int64("t", 0, "")
-	config         = flag.String("cfg", "embed:null", "path to config")
+	config         = flag.String("cfg", "", "configurator URI <factory>:<path>, e.g. file:/etc/gcoredumper/config.prototxt")
 )
 
-func SetUpLogger(w io.Writer) {
+func SetUpLogger(w io.Writer, reportID string) {
 	log.SetOutput(w)
-	log.SetPrefix(fmt.Sprintf("%v: ", uuid.NewString()))
+	log.SetPrefix(fmt.Sprintf("%v: ", reportID))
 }
 
-func main() {
-	flag.Parse()
-	reporter := report.New()
-	// TODO(noxiouz): make configurable
-	cfg, err := configurator.Open("embed", "")
+// loadConfig loads the config through the configurator fallback chain and
+// records its URI as config.source. Failures worth reporting are recorded
+// as config.error.
+func loadConfig(ctx context.Context, reporter *report.Report) (*configuration.Config, error) {
+	cfg, source, err := configurator.Load(ctx, *config, func(err error) {
+		reporter.AddError("config.error", err)
+	})
 	if err != nil {
-		log.Fatalf("%v", err)
+		return nil, err
 	}
+	reporter.AddString("config.source", source)
+	return cfg, nil
+}
 
-	config, err := cfg.Get(context.Background())
-	if err != nil {
-		log.Fatalf("%v", err)
-	}
-	f, err := os.OpenFile(config.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		log.Println(err)
-		SetUpLogger(io.Discard)
-	} else {
-		SetUpLogger(f)
-		defer f.Close()
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("Recovered in main %v", r)
+// subcommands are invoked by an operator, not by the kernel.
+var subcommands = map[string]func(args []string) error{
+	"install":   installCmd,
+	"uninstall": uninstallCmd,
+	"replay":    replayCmd,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
 		}
-	}()
-	sink := &report.LogBasedReporter{Logger: log.Default()}
-	defer reporter.Report(sink)
-	log.Println("Start dump")
+	}
 
-	ctx := report.WithReport(context.Background(), reporter)
-	// TODO: add required arguments check
-	si := core.SystemInput{
+	flag.Parse()
+	if err := handle(core.SystemInput{
 		// Pathname of Executable
 		Executable: *executable,
 		// TID in initial namespace
@@ -86,12 +85,66 @@ func main() {
 		Signal: syscall.Signal(*signalNum),
 		// Input
 		Stream: os.Stdin,
+	}, nil); err != nil {
+		log.Fatalf("%v", err)
 	}
+}
 
-	err = core.Run(ctx, si, config)
+// handle runs the whole pipeline for a single crash described by si.
+// Logs go to logOutput, or to logFile of the config if it is nil.
+// The error is of loading the config, of validating si or of core.Run,
+// the report is delivered for the latter two.
+func handle(si core.SystemInput, logOutput io.Writer) (err error) {
+	reporter := report.New()
+	config, err := loadConfig(context.Background(), reporter)
 	if err != nil {
+		return err
+	}
+	if logOutput != nil {
+		SetUpLogger(logOutput, reporter.ID())
+	} else if f, err := os.OpenFile(config.LogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666); err != nil {
+		log.Println(err)
+		SetUpLogger(io.Discard, reporter.ID())
+	} else {
+		SetUpLogger(f, reporter.ID())
+		defer f.Close()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered in main %v", r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	sink, err := report.NewSinks(report.ConfiguredSinks(config), log.Default())
+	if err != nil {
+		log.Printf("unable to create report sinks: %v", err)
+	}
+	defer sink.Close()
+	defer func() {
+		if err := reporter.Report(sink); err != nil {
+			log.Printf("unable to deliver report: %v", err)
+		}
+	}()
+	log.Println("Start dump")
+
+	ctx := report.WithReport(context.Background(), reporter)
+	if err := si.Validate(ctx); err != nil {
+		log.Println(err)
+		// Do not block the kernel on a pipe nobody reads
+		discarded, discardErr := io.Copy(io.Discard, si.Stream)
+		reporter.AddInt("input.discarded", discarded)
+		if discardErr != nil {
+			reporter.AddError("input.discard.error", discardErr)
+		}
+		reporter.AddString("dump.status", "skipped")
+		return err
+	}
+
+	if err := core.Run(ctx, si, config); err != nil {
 		log.Printf("Run returned an error %v", err)
 		reporter.AddError("core.run.error", err)
+		return err
 	}
 	log.Println("Done")
+	return nil
 }
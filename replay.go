@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/core"
+)
+
+// replayCmd feeds a core file from disk through the same pipeline
+// the kernel-invoked handler uses. Logs go to stderr. The error of
+// the pipeline is returned, so replay exits non-zero if it fails.
+func replayCmd(args []string) error {
+	fset := flag.NewFlagSet("replay", flag.ExitOnError)
+	corefile := fset.String("core", "", "core file to replay")
+	exe := fset.String("exe", "", "executable of the crashed process")
+	pid := fset.Int64("pid", 0, "PID of the crashed process")
+	tid := fset.Int64("tid", 0, "TID of the crashed thread, defaults to -pid")
+	signalNum := fset.Int("signal", int(syscall.SIGSEGV), "signal number")
+	timestampInSec := fset.Int64("t", 0, "time of dump, defaults to mtime of -core")
+	root := fset.String("root", "", "directory with an archived <root>/proc/<pid> snapshot, a synthetic one is generated from -exe if empty")
+	fset.StringVar(config, "cfg", "", "configurator URI <factory>:<path>")
+	fset.Parse(args)
+
+	if *corefile == "" {
+		return errors.New("-core is required")
+	}
+	if *pid <= 0 {
+		return errors.New("-pid is required")
+	}
+	if *tid <= 0 {
+		*tid = *pid
+	}
+
+	stream, err := os.Open(*corefile)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	dumpTime := time.Unix(*timestampInSec, 0)
+	if *timestampInSec == 0 {
+		stat, err := stream.Stat()
+		if err != nil {
+			return err
+		}
+		dumpTime = stat.ModTime()
+	}
+
+	if *root == "" {
+		if *exe == "" {
+			return errors.New("either -root or -exe is required")
+		}
+		dir, err := os.MkdirTemp("", "gcoredumper-replay")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := makeSyntheticProc(dir, *pid, *tid, *exe); err != nil {
+			return err
+		}
+		*root = dir
+	}
+	rootFs := afero.NewBasePathFs(afero.NewOsFs(), *root)
+	if *exe == "" {
+		if *exe, err = rootFs.(afero.LinkReader).ReadlinkIfPossible(fmt.Sprintf("/proc/%d/exe", *pid)); err != nil {
+			return err
+		}
+	}
+
+	return handle(core.SystemInput{
+		Executable: strings.ReplaceAll(*exe, "/", "!"),
+		InitialTid: *tid,
+		NsTid:      *tid,
+		// Replayed cores are always dumpable
+		PrGetDumpable: core.DUMPABLE_DEFAULT,
+		InitialPid:    *pid,
+		NsPid:         *pid,
+		DumpTime:      dumpTime,
+		Signal:        syscall.Signal(*signalNum),
+		Stream:        stream,
+		Filesystem:    rootFs,
+	}, os.Stderr)
+}
+
+// makeSyntheticProc creates the minimal <dir>/proc/<pid> layout
+// ProcessInfo needs: cmdline, environ, exe link and task/<tid>.
+func makeSyntheticProc(dir string, pid int64, tid int64, exe string) error {
+	exe, err := filepath.Abs(exe)
+	if err != nil {
+		return err
+	}
+	procDir := filepath.Join(dir, "proc", strconv.FormatInt(pid, 10))
+	if err := os.MkdirAll(filepath.Join(procDir, "task", strconv.FormatInt(tid, 10)), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(procDir, "cmdline"), []byte(exe+"\x00"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(procDir, "environ"), nil, 0644); err != nil {
+		return err
+	}
+	return os.Symlink(exe, filepath.Join(procDir, "exe"))
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+// NewSinks creates a MultiSink from configs. Sinks failed to be created
+// are skipped and returned as MultiError along with the rest.
+// LogBasedReporter writing to logger is used if configs is empty.
+func NewSinks(configs []*configuration.Config_Sink, logger *log.Logger) (*MultiSink, error) {
+	if len(configs) == 0 {
+		return NewMultiSink(&LogBasedReporter{Logger: logger}), nil
+	}
+
+	var (
+		sinks []Sink
+		errs  MultiError
+	)
+	for i, cfg := range configs {
+		sink, err := newSink(cfg, logger)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink #%d: %w", i, err))
+			continue
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(errs) > 0 {
+		return NewMultiSink(sinks...), errs
+	}
+	return NewMultiSink(sinks...), nil
+}
+
+// ConfiguredSinks returns sinks of config including jsonReportFile.
+// Log sink is kept if jsonReportFile is the only one set.
+func ConfiguredSinks(config *configuration.Config) []*configuration.Config_Sink {
+	sinks := config.GetSinks()
+	path := config.GetJsonReportFile()
+	if path == "" {
+		return sinks
+	}
+	if len(sinks) == 0 {
+		sinks = []*configuration.Config_Sink{{
+			Sink: &configuration.Config_Sink_Log_{Log: new(configuration.Config_Sink_Log)},
+		}}
+	}
+	jsonl := &configuration.Config_Sink{
+		Sink: &configuration.Config_Sink_Jsonl{Jsonl: &configuration.Config_Sink_JSONLines{Path: path}},
+	}
+	return append(append([]*configuration.Config_Sink(nil), sinks...), jsonl)
+}
+
+func newSink(cfg *configuration.Config_Sink, logger *log.Logger) (Sink, error) {
+	switch s := cfg.GetSink().(type) {
+	case *configuration.Config_Sink_Log_:
+		return &LogBasedReporter{Logger: logger}, nil
+	case *configuration.Config_Sink_Jsonl:
+		return OpenJSONSink(s.Jsonl.GetPath())
+	case *configuration.Config_Sink_Syslog_:
+		return NewSyslogSink(s.Syslog.GetNetwork(), s.Syslog.GetAddress(), s.Syslog.GetTag())
+	case *configuration.Config_Sink_Webhook_:
+		return NewWebhookSink(s.Webhook.GetUrl(), time.Duration(s.Webhook.GetTimeoutMs())*time.Millisecond), nil
+	case *configuration.Config_Sink_ProtoFile_:
+		return OpenProtoFileSink(s.ProtoFile.GetPath())
+	default:
+		return nil, fmt.Errorf("unknown sink type %T", s)
+	}
+}
@@ -0,0 +1,55 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/noxiouz/gcoredumper/configuration"
+)
+
+func TestConfiguredSinks(t *testing.T) {
+	logSink := &configuration.Config_Sink{
+		Sink: &configuration.Config_Sink_Log_{Log: new(configuration.Config_Sink_Log)},
+	}
+	jsonl := func(path string) *configuration.Config_Sink {
+		return &configuration.Config_Sink{
+			Sink: &configuration.Config_Sink_Jsonl{Jsonl: &configuration.Config_Sink_JSONLines{Path: path}},
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		config *configuration.Config
+		want   []*configuration.Config_Sink
+	}{
+		{
+			name:   "empty",
+			config: &configuration.Config{},
+		},
+		{
+			name:   "sinks only",
+			config: &configuration.Config{Sinks: []*configuration.Config_Sink{jsonl("/var/log/a.jsonl")}},
+			want:   []*configuration.Config_Sink{jsonl("/var/log/a.jsonl")},
+		},
+		{
+			name:   "jsonReportFile keeps the log sink",
+			config: &configuration.Config{JsonReportFile: "fd:3"},
+			want:   []*configuration.Config_Sink{logSink, jsonl("fd:3")},
+		},
+		{
+			name: "jsonReportFile in addition to sinks",
+			config: &configuration.Config{
+				JsonReportFile: "fd:3",
+				Sinks:          []*configuration.Config_Sink{jsonl("/var/log/a.jsonl")},
+			},
+			want: []*configuration.Config_Sink{jsonl("/var/log/a.jsonl"), jsonl("fd:3")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, ConfiguredSinks(tc.config), protocmp.Transform()); diff != "" {
+				t.Errorf("ConfiguredSinks() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MarshalJSON encodes the report as a JSONSink does,
+// so sidecars and JSON-lines reports share one schema.
+func (r *Report) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := r.Report(NewJSONSink(&buf)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+var _ json.Marshaler = (*Report)(nil)
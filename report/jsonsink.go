@@ -0,0 +1,159 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONSink writes all records of a crash as a single JSON object per line.
+// Records are flattened into typed fields: numbers and strings as is,
+// durations in milliseconds, stacktraces and modules as arrays of objects.
+// Every line carries report.uuid and report.timestamp of the crash, so
+// consumers can deduplicate redelivered reports.
+type JSONSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	fields map[string]interface{}
+}
+
+type jsonFrame struct {
+	Func   string `json:"func,omitempty"`
+	Addr   uint64 `json:"addr"`
+	Line   uint64 `json:"line,omitempty"`
+	Module string `json:"module,omitempty"`
+	Offset uint64 `json:"offset,omitempty"`
+	File   string `json:"file,omitempty"`
+}
+
+type jsonModule struct {
+	Path    string `json:"path"`
+	Start   uint64 `json:"start"`
+	End     uint64 `json:"end"`
+	Inode   uint64 `json:"inode,omitempty"`
+	BuildID string `json:"build_id,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// repeatedKeys may be logged several times per crash. They are always
+// arrays, so the type of a field does not depend on the crash.
+// Other keys logged again replace the previous value.
+var repeatedKeys = map[string]bool{
+	"cgroup":             true,
+	"core.stale.removed": true,
+	"retention.evicted":  true,
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{
+		w:      w,
+		fields: make(map[string]interface{}),
+	}
+}
+
+// OpenJSONSink opens a JSONSink writing to target which is
+// either a file path or fd:<N> for an inherited file descriptor.
+func OpenJSONSink(target string) (*JSONSink, error) {
+	if strings.HasPrefix(target, "fd:") {
+		fd, err := strconv.Atoi(strings.TrimPrefix(target, "fd:"))
+		if err != nil {
+			return nil, fmt.Errorf("malformed fd in %q: %w", target, err)
+		}
+		return NewJSONSink(os.NewFile(uintptr(fd), target)), nil
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONSink(f), nil
+}
+
+func (j *JSONSink) Log(record *Record) {
+	var value interface{}
+	switch v := record.Value.(type) {
+	case *Record_Number:
+		value = v.Number
+	case *Record_Str:
+		value = v.Str
+	case *Record_Duration:
+		value = float64(v.Duration.AsDuration().Microseconds()) / 1000
+	case *Record_Buf:
+		value = v.Buf
+	case *Record_Stacktrace:
+		frames := make([]jsonFrame, 0, len(v.Stacktrace.GetFrames()))
+		for _, frame := range v.Stacktrace.GetFrames() {
+			frames = append(frames, jsonFrame{
+				Func:   frame.GetFunc(),
+				Addr:   frame.GetAddr(),
+				Line:   frame.GetLine(),
+				Module: frame.GetModule(),
+				Offset: frame.GetOffset(),
+				File:   frame.GetFile(),
+			})
+		}
+		value = frames
+	case *Record_Modules:
+		modules := make([]jsonModule, 0, len(v.Modules.GetModules()))
+		for _, module := range v.Modules.GetModules() {
+			modules = append(modules, jsonModule{
+				Path:    module.GetPath(),
+				Start:   module.GetStart(),
+				End:     module.GetEnd(),
+				Inode:   module.GetInode(),
+				BuildID: module.GetBuildId(),
+				Deleted: module.GetDeleted(),
+			})
+		}
+		value = modules
+	default:
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !repeatedKeys[record.Name] {
+		j.fields[record.Name] = value
+		return
+	}
+	values, _ := j.fields[record.Name].([]interface{})
+	j.fields[record.Name] = append(values, value)
+}
+
+// Start records the identity of the crash.
+func (j *JSONSink) Start(report *CrashReport) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.fields["report.uuid"] = report.GetUuid()
+	j.fields["report.timestamp"] = report.GetTimestamp().AsTime().UTC().Format(time.RFC3339Nano)
+}
+
+// Flush writes collected records as one line and resets the sink.
+func (j *JSONSink) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	body, err := json.Marshal(j.fields)
+	if err != nil {
+		return err
+	}
+	j.fields = make(map[string]interface{})
+	_, err = j.w.Write(append(body, '\n'))
+	return err
+}
+
+func (j *JSONSink) Close() error {
+	if closer, ok := j.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+var (
+	_ Sink    = (*JSONSink)(nil)
+	_ Flusher = (*JSONSink)(nil)
+	_ Starter = (*JSONSink)(nil)
+)
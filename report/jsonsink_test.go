@@ -0,0 +1,68 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONSink(t *testing.T) {
+	r := New()
+	r.AddInt("pid.global", 100)
+	r.AddString("binary", "foo")
+	r.AddDuration("core.dumpingduration", 1500*time.Microsecond)
+	r.AddString("retention.evicted", "/cores/foo.1.1")
+	r.AddString("retention.evicted", "/cores/foo.2.2")
+	// declared repeated, an array even if logged once
+	r.AddString("cgroup", "0::/system.slice/foo.service")
+	// not declared repeated, the last value wins
+	r.AddString("signal.name", "SIGABRT")
+	r.AddString("signal.name", "SIGSEGV")
+	r.AddStackTrace("stacktrace", &StackTrace{
+		Frames: []*StackTrace_Frame{{Func: "main", Addr: 0x1000, Line: 10}},
+	})
+	r.AddModules("modules", &Modules{
+		Modules: []*Modules_Module{{Path: "/lib/libc.so.6", Start: 0x1000, End: 0x2000, Inode: 42, BuildId: "abcd"}},
+	})
+
+	buf := new(bytes.Buffer)
+	sink := NewJSONSink(buf)
+	for i := 0; i < 2; i++ {
+		if err := r.Report(sink); err != nil {
+			t.Fatalf("Report() returned unexpected error %v", err)
+		}
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.Bytes())
+	}
+	want := map[string]interface{}{
+		"report.uuid":          r.ID(),
+		"report.timestamp":     r.CrashReport().GetTimestamp().AsTime().UTC().Format(time.RFC3339Nano),
+		"pid.global":           float64(100),
+		"binary":               "foo",
+		"core.dumpingduration": 1.5,
+		"retention.evicted":    []interface{}{"/cores/foo.1.1", "/cores/foo.2.2"},
+		"cgroup":               []interface{}{"0::/system.slice/foo.service"},
+		"signal.name":          "SIGSEGV",
+		"stacktrace": []interface{}{
+			map[string]interface{}{"func": "main", "addr": float64(0x1000), "line": float64(10)},
+		},
+		"modules": []interface{}{
+			map[string]interface{}{"path": "/lib/libc.so.6", "start": float64(0x1000), "end": float64(0x2000), "inode": float64(42), "build_id": "abcd"},
+		},
+	}
+	for _, line := range lines {
+		var got map[string]interface{}
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("line is not a valid JSON %v: %s", err, line)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("JSONSink mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
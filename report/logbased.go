@@ -17,5 +17,13 @@ func (l *LogBasedReporter) Log(record *Record) {
 		log.Printf("%s = %d", key, value)
 	case *Record_Duration:
 		log.Printf("%s = %v", key, value)
+	case *Record_Stacktrace:
+		for i, frame := range value.Stacktrace.GetFrames() {
+			log.Printf("%s #%d %#x %s+%#x %s:%d (%s)", key, i, frame.Addr, frame.Func, frame.Offset, frame.File, frame.Line, frame.Module)
+		}
+	case *Record_Modules:
+		for _, module := range value.Modules.GetModules() {
+			log.Printf("%s %#x-%#x %s %s", key, module.Start, module.End, module.Path, module.BuildId)
+		}
 	}
 }
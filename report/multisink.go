@@ -0,0 +1,122 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MultiSink delivers every record to all sinks. A failure or a panic
+// of one sink does not prevent delivery to the others.
+type MultiSink struct {
+	sinks []Sink
+	errs  MultiError
+}
+
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) Log(record *Record) {
+	for _, s := range m.sinks {
+		m.capture(s, func() error {
+			s.Log(record)
+			return nil
+		})
+	}
+}
+
+// LogCrashReport delivers report to every sink in the most suitable form.
+// Start, records and Flush are captured one by one, so a record a sink panics on
+// does not cost the rest of them.
+func (m *MultiSink) LogCrashReport(report *CrashReport) error {
+	for _, s := range m.sinks {
+		s := s
+		if cs, ok := s.(CrashReportSink); ok {
+			m.capture(s, func() error {
+				return cs.LogCrashReport(report)
+			})
+			continue
+		}
+		if st, ok := s.(Starter); ok {
+			m.capture(s, func() error {
+				st.Start(report)
+				return nil
+			})
+		}
+		for _, record := range report.GetRecords() {
+			m.capture(s, func() error {
+				s.Log(record)
+				return nil
+			})
+		}
+		if f, ok := s.(Flusher); ok {
+			m.capture(s, f.Flush)
+		}
+	}
+	errs := m.errs
+	m.errs = nil
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Flush flushes all sinks and returns failures of individual sinks
+// collected since the previous Flush as MultiError.
+func (m *MultiSink) Flush() error {
+	for _, s := range m.sinks {
+		if f, ok := s.(Flusher); ok {
+			m.capture(s, f.Flush)
+		}
+	}
+	errs := m.errs
+	m.errs = nil
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *MultiSink) Close() error {
+	var errs MultiError
+	for _, s := range m.sinks {
+		if closer, ok := s.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%T: %w", s, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (m *MultiSink) capture(s Sink, fn func() error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.errs = append(m.errs, fmt.Errorf("%T panicked: %v", s, r))
+		}
+	}()
+	if err := fn(); err != nil {
+		m.errs = append(m.errs, fmt.Errorf("%T: %w", s, err))
+	}
+}
+
+// MultiError is a list of errors of independent operations.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, 0, len(m))
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var (
+	_ Sink            = (*MultiSink)(nil)
+	_ Flusher         = (*MultiSink)(nil)
+	_ CrashReportSink = (*MultiSink)(nil)
+)
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type failingSink struct{}
+
+func (failingSink) Log(record *Record) {
+	panic("boom")
+}
+
+func (failingSink) Flush() error {
+	return errors.New("flush failed")
+}
+
+func TestMultiSinkDeliversDespiteFailures(t *testing.T) {
+	r := New()
+	r.AddInt("pid.global", 100)
+
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	sink := NewMultiSink(NewJSONSink(first), failingSink{}, NewJSONSink(second))
+	err := r.Report(sink)
+
+	var errs MultiError
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("Report() = %v, want panic and flush errors", err)
+	}
+	for _, buf := range []*bytes.Buffer{first, second} {
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("sink got an invalid JSON %v: %q", err, buf.String())
+		}
+		if got["pid.global"] != float64(100) || got["report.uuid"] != r.ID() {
+			t.Errorf("sink got %q, want pid.global and report.uuid of the report", buf.String())
+		}
+	}
+	if err := r.Report(NewMultiSink(NewJSONSink(first))); err != nil {
+		t.Errorf("Report() returned unexpected error %v", err)
+	}
+}
+
+// pickySink panics on a single record
+type pickySink struct {
+	panicOn string
+	logged  []string
+	flushed bool
+}
+
+func (p *pickySink) Log(record *Record) {
+	if record.Name == p.panicOn {
+		panic("boom")
+	}
+	p.logged = append(p.logged, record.Name)
+}
+
+func (p *pickySink) Flush() error {
+	p.flushed = true
+	return nil
+}
+
+func TestMultiSinkIsolatesRecords(t *testing.T) {
+	r := New()
+	r.AddInt("pid.global", 100)
+	r.AddString("binary", "server")
+	r.AddInt("signal", 11)
+
+	sink := &pickySink{panicOn: "binary"}
+	var errs MultiError
+	if err := r.Report(NewMultiSink(sink)); !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf("Report() = %v, want a single panic error", err)
+	}
+	if got, want := strings.Join(sink.logged, ","), "pid.global,signal"; got != want {
+		t.Errorf("logged records %q, want %q", got, want)
+	}
+	if !sink.flushed {
+		t.Error("sink is not flushed after a panic")
+	}
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtoFileSink appends CrashReports to a file, each prefixed
+// with its varint-encoded length. Use ReadCrashReport to read them back.
+type ProtoFileSink struct {
+	w io.Writer
+}
+
+func NewProtoFileSink(w io.Writer) *ProtoFileSink {
+	return &ProtoFileSink{w: w}
+}
+
+func OpenProtoFileSink(path string) (*ProtoFileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewProtoFileSink(f), nil
+}
+
+// Log is no-op, ProtoFileSink gets records via LogCrashReport.
+func (p *ProtoFileSink) Log(record *Record) {}
+
+func (p *ProtoFileSink) LogCrashReport(report *CrashReport) error {
+	body, err := proto.Marshal(report)
+	if err != nil {
+		return err
+	}
+	buf := protowire.AppendVarint(make([]byte, 0, len(body)+binary.MaxVarintLen64), uint64(len(body)))
+	// a single write keeps concurrent appenders from interleaving
+	_, err = p.w.Write(append(buf, body...))
+	return err
+}
+
+func (p *ProtoFileSink) Close() error {
+	if closer, ok := p.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ReadCrashReport reads a single length-delimited CrashReport.
+// It returns io.EOF when there are no more reports.
+func ReadCrashReport(r *bufio.Reader) (*CrashReport, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	report := new(CrashReport)
+	if err := proto.Unmarshal(body, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+var (
+	_ Sink            = (*ProtoFileSink)(nil)
+	_ CrashReportSink = (*ProtoFileSink)(nil)
+)
@@ -0,0 +1,41 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestProtoFileSinkRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sink := NewProtoFileSink(buf)
+	var want []*CrashReport
+	for _, binary := range []string{"foo", "bar"} {
+		r := New()
+		r.AddString("binary", binary)
+		if err := r.Report(sink); err != nil {
+			t.Fatalf("Report() returned unexpected error %v", err)
+		}
+		want = append(want, r.CrashReport())
+	}
+
+	var got []*CrashReport
+	rd := bufio.NewReader(buf)
+	for {
+		report, err := ReadCrashReport(rd)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadCrashReport() returned unexpected error %v", err)
+		}
+		got = append(got, report)
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("ReadCrashReport() mismatch (-want +got):\n%s", diff)
+	}
+}
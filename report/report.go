@@ -2,10 +2,13 @@ package report
 
 import (
 	"context"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
@@ -28,14 +31,28 @@ func GetReport(ctx context.Context) *Report {
 	return defaultReport
 }
 
+// CrashReportSchemaVersion is stored in every CrashReport.
+// Bump it on incompatible changes of record names or types.
+const CrashReportSchemaVersion = 1
+
 // Report provides an interface to collect specific types of key-value pairs.
 type Report struct {
 	mu      sync.Mutex
+	id      string
+	started time.Time
 	records []*Record
 }
 
 func New() *Report {
-	return &Report{}
+	return &Report{
+		id:      uuid.NewString(),
+		started: time.Now(),
+	}
+}
+
+// ID returns UUID of the report.
+func (r *Report) ID() string {
+	return r.id
 }
 
 // AddInt adds int64 value to report
@@ -74,20 +91,90 @@ func (r *Report) AddDuration(key string, value time.Duration) {
 	})
 }
 
+// AddStackTrace adds symbolized stacktrace to report
+func (r *Report) AddStackTrace(key string, st *StackTrace) {
+	r.add(&Record{
+		Name:  key,
+		Value: &Record_Stacktrace{st},
+	})
+}
+
+// AddModules adds mapped files to report
+func (r *Report) AddModules(key string, modules *Modules) {
+	r.add(&Record{
+		Name:  key,
+		Value: &Record_Modules{modules},
+	})
+}
+
 func (r *Report) add(record *Record) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.records = append(r.records, record)
 }
 
-func (r *Report) Report(s Sink) {
+// Records returns a snapshot of collected records.
+func (r *Report) Records() []*Record {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for _, record := range r.records {
+	return append([]*Record(nil), r.records...)
+}
+
+// CrashReport returns a snapshot of the report as a CrashReport message.
+func (r *Report) CrashReport() *CrashReport {
+	host, _ := os.Hostname()
+	return &CrashReport{
+		SchemaVersion: CrashReportSchemaVersion,
+		Uuid:          r.id,
+		Timestamp:     timestamppb.New(r.started),
+		Host:          host,
+		Records:       r.Records(),
+	}
+}
+
+// Report delivers all collected records to s.
+// CrashReportSink gets them as a whole, other sinks record by record
+// preceded by Start if s implements Starter and followed by Flush
+// if s implements Flusher.
+func (r *Report) Report(s Sink) error {
+	report := r.CrashReport()
+	if cs, ok := s.(CrashReportSink); ok {
+		return cs.LogCrashReport(report)
+	}
+	return logRecords(s, report)
+}
+
+func logRecords(s Sink, report *CrashReport) error {
+	if st, ok := s.(Starter); ok {
+		st.Start(report)
+	}
+	for _, record := range report.GetRecords() {
 		s.Log(record)
 	}
+	if f, ok := s.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
 }
 
 type Sink interface {
 	Log(record *Record)
 }
+
+// Starter is implemented by sinks which need the identity of a crash,
+// Start is called before its records are logged.
+type Starter interface {
+	Start(report *CrashReport)
+}
+
+// Flusher is implemented by sinks which deliver a crash as a whole
+// after all its records have been logged.
+type Flusher interface {
+	Flush() error
+}
+
+// CrashReportSink is implemented by sinks which need the whole
+// CrashReport rather than separate records.
+type CrashReportSink interface {
+	LogCrashReport(report *CrashReport) error
+}
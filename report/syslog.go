@@ -0,0 +1,18 @@
+package report
+
+import (
+	"log/syslog"
+)
+
+// SyslogSink sends one JSON object per crash to syslog.
+type SyslogSink struct {
+	*JSONSink
+}
+
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_CRIT|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{NewJSONSink(w)}, nil
+}
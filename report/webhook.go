@@ -0,0 +1,47 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// WebhookSink POSTs one JSON object per crash to url.
+type WebhookSink struct {
+	*JSONSink
+	buf    *bytes.Buffer
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	buf := new(bytes.Buffer)
+	return &WebhookSink{
+		JSONSink: NewJSONSink(buf),
+		buf:      buf,
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *WebhookSink) Flush() error {
+	defer w.buf.Reset()
+	if err := w.JSONSink.Flush(); err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", w.buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded %s", w.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package symbolizer
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"debug/gosym"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/report"
+	"github.com/noxiouz/gcoredumper/utils/procmaps"
+)
+
+const deletedSuffix = " (deleted)"
+
+// Symbolizer resolves virtual addresses of a process into functions
+// using /proc/<pid>/maps and symbol tables and DWARF of mapped ELF files.
+type Symbolizer struct {
+	procFs   afero.Fs
+	mappings []procmaps.Mapping
+	modules  map[string]*module
+	// failed keeps errors of modules which could not be opened
+	failed map[string]error
+}
+
+// New creates a Symbolizer for a process. procFs is rooted at /proc/<pid>,
+// mappings are parsed from its maps.
+func New(procFs afero.Fs, mappings []procmaps.Mapping) *Symbolizer {
+	return &Symbolizer{
+		procFs:   procFs,
+		mappings: mappings,
+		modules:  make(map[string]*module),
+		failed:   make(map[string]error),
+	}
+}
+
+// Symbolize resolves addrs of a stack, the innermost frame first.
+// Unresolved addresses are kept as frames with Addr only.
+func (s *Symbolizer) Symbolize(addrs []uint64) *report.StackTrace {
+	st := new(report.StackTrace)
+	for i, addr := range addrs {
+		frame := &report.StackTrace_Frame{Addr: addr}
+		st.Frames = append(st.Frames, frame)
+
+		m, ok := procmaps.Find(s.mappings, addr)
+		if !ok {
+			continue
+		}
+		frame.Module = strings.TrimSuffix(m.Path, deletedSuffix)
+		if !m.IsFile() {
+			continue
+		}
+		mod, err := s.module(m)
+		if err != nil {
+			continue
+		}
+		pc, ok := mod.elfAddr(addr - m.Start + m.Offset)
+		if !ok {
+			continue
+		}
+		// Outer frames hold return addresses: look up the call instruction.
+		lookupPC := pc
+		if i > 0 && lookupPC > 0 {
+			lookupPC--
+		}
+		mod.resolve(frame, pc, lookupPC)
+	}
+	return st
+}
+
+func (s *Symbolizer) Close() error {
+	for _, mod := range s.modules {
+		mod.Close()
+	}
+	s.modules = nil
+	return nil
+}
+
+func (s *Symbolizer) module(m *procmaps.Mapping) (*module, error) {
+	if mod, ok := s.modules[m.Path]; ok {
+		return mod, nil
+	}
+	if err, ok := s.failed[m.Path]; ok {
+		return nil, err
+	}
+	mod, err := openModule(s.procFs, m)
+	if err != nil {
+		s.failed[m.Path] = fmt.Errorf("unable to open %s: %w", m.Path, err)
+		return nil, s.failed[m.Path]
+	}
+	s.modules[m.Path] = mod
+	return mod, nil
+}
+
+// openModule opens the file of m through map_files/, which refers to it
+// even if it is deleted or belongs to another mount namespace. root/ of the
+// process is the fallback, it resolves the path inside its mount namespace.
+func openModule(procFs afero.Fs, m *procmaps.Mapping) (*module, error) {
+	f, err := procFs.Open(fmt.Sprintf("map_files/%x-%x", m.Start, m.End))
+	if err != nil {
+		var rootErr error
+		if f, rootErr = procFs.Open(path.Join("root", strings.TrimSuffix(m.Path, deletedSuffix))); rootErr != nil {
+			return nil, err
+		}
+	}
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return newModule(ef, f), nil
+}
+
+// module is a mapped ELF file
+type module struct {
+	*elf.File
+	closer  io.Closer
+	symbols []elf.Symbol
+	dwarf   *dwarf.Data
+	// Go binaries keep pclntab even when stripped
+	gosym *gosym.Table
+}
+
+func newModule(ef *elf.File, closer io.Closer) *module {
+	mod := &module{
+		File:    ef,
+		closer:  closer,
+		symbols: functionSymbols(ef),
+		gosym:   goTable(ef),
+	}
+	if mod.gosym == nil {
+		// DWARF is optional
+		mod.dwarf, _ = ef.DWARF()
+	}
+	return mod
+}
+
+func (mod *module) Close() error {
+	mod.File.Close()
+	return mod.closer.Close()
+}
+
+// elfAddr converts a file offset into a virtual address of the ELF file.
+func (mod *module) elfAddr(fileOffset uint64) (uint64, bool) {
+	for _, prog := range mod.Progs {
+		if prog.Type != elf.PT_LOAD {
+			continue
+		}
+		if prog.Off <= fileOffset && fileOffset < prog.Off+prog.Filesz {
+			return fileOffset - prog.Off + prog.Vaddr, true
+		}
+	}
+	return 0, false
+}
+
+func (mod *module) resolve(frame *report.StackTrace_Frame, pc uint64, lookupPC uint64) {
+	if mod.gosym != nil {
+		if file, line, fn := mod.gosym.PCToLine(lookupPC); fn != nil {
+			frame.Func = fn.Name
+			frame.Offset = pc - fn.Entry
+			frame.File = file
+			frame.Line = uint64(line)
+			return
+		}
+	}
+	if sym, ok := findSymbol(mod.symbols, lookupPC); ok {
+		frame.Func = sym.Name
+		frame.Offset = pc - sym.Value
+	}
+	if mod.dwarf != nil {
+		if file, line, ok := dwarfLine(mod.dwarf, lookupPC); ok {
+			frame.File = file
+			frame.Line = uint64(line)
+		}
+	}
+}
+
+func dwarfLine(d *dwarf.Data, pc uint64) (string, int, bool) {
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil || entry == nil {
+			return "", 0, false
+		}
+		if entry.Tag != dwarf.TagCompileUnit {
+			r.SkipChildren()
+			continue
+		}
+		ranges, err := d.Ranges(entry)
+		if err != nil {
+			r.SkipChildren()
+			continue
+		}
+		for _, rng := range ranges {
+			if rng[0] <= pc && pc < rng[1] {
+				lr, err := d.LineReader(entry)
+				if err != nil || lr == nil {
+					return "", 0, false
+				}
+				var le dwarf.LineEntry
+				if err := lr.SeekPC(pc, &le); err != nil {
+					return "", 0, false
+				}
+				return le.File.Name, le.Line, true
+			}
+		}
+		r.SkipChildren()
+	}
+}
@@ -0,0 +1,214 @@
+package symbolizer
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/noxiouz/gcoredumper/utils/procmaps"
+)
+
+// newSymbolizer creates a Symbolizer from maps of procFs
+func newSymbolizer(t *testing.T, procFs afero.Fs) *Symbolizer {
+	t.Helper()
+	maps, err := procFs.Open("maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer maps.Close()
+	mappings, err := procmaps.Parse(maps)
+	if err != nil {
+		t.Fatalf("procmaps.Parse() = %v", err)
+	}
+	return New(procFs, mappings)
+}
+
+// pickFunction returns a sized function symbol from .dynsym
+// along with names of all its aliases.
+func pickFunction(t *testing.T, ef *elf.File) (elf.Symbol, map[string]bool) {
+	t.Helper()
+	syms, err := ef.DynamicSymbols()
+	if err != nil {
+		t.Skipf("no dynamic symbols: %v", err)
+	}
+	var picked *elf.Symbol
+	for i := range syms {
+		if elf.ST_TYPE(syms[i].Info) == elf.STT_FUNC && syms[i].Size > 16 {
+			picked = &syms[i]
+			break
+		}
+	}
+	if picked == nil {
+		t.Skip("no function symbols")
+	}
+	aliases := make(map[string]bool)
+	for _, sym := range syms {
+		if sym.Value == picked.Value {
+			aliases[sym.Name] = true
+		}
+	}
+	return *picked, aliases
+}
+
+func TestSymbolizeSharedLibrary(t *testing.T) {
+	var library string
+	for _, candidate := range []string{
+		"/lib/x86_64-linux-gnu/libc.so.6",
+		"/usr/lib/x86_64-linux-gnu/libc.so.6",
+		"/lib64/libc.so.6",
+	} {
+		if _, err := os.Stat(candidate); err == nil {
+			library = candidate
+			break
+		}
+	}
+	if library == "" {
+		t.Skip("no shared library to test with")
+	}
+	ef, err := elf.Open(library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ef.Close()
+	sym, aliases := pickFunction(t, ef)
+
+	body, err := os.ReadFile(library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		// suffix of the path in maps
+		suffix string
+		// files returns paths under /proc/1 the library is readable at
+		files func(mappings []string) []string
+	}{
+		{
+			name:  "root",
+			files: func([]string) []string { return []string{path.Join("root", library)} },
+		},
+		{
+			name:   "deleted via map_files",
+			suffix: " (deleted)",
+			files: func(mappings []string) []string {
+				var files []string
+				for _, m := range mappings {
+					files = append(files, path.Join("map_files", m))
+				}
+				return files
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Map every PT_LOAD segment at a fake load address like ld.so does
+			const base = 0x7f0000000000
+			fs := afero.NewMemMapFs()
+			var maps string
+			var mappings []string
+			for _, prog := range ef.Progs {
+				if prog.Type != elf.PT_LOAD {
+					continue
+				}
+				perms := "r--p"
+				if prog.Flags&elf.PF_X != 0 {
+					perms = "r-xp"
+				}
+				start := base + prog.Vaddr&^0xfff
+				end := base + (prog.Vaddr+prog.Memsz+0xfff)&^0xfff
+				maps += fmt.Sprintf("%x-%x %s %08x fd:01 42 %s%s\n", start, end, perms, prog.Off&^0xfff, library, tc.suffix)
+				mappings = append(mappings, fmt.Sprintf("%x-%x", start, end))
+			}
+			if err := afero.WriteFile(fs, "/proc/1/maps", []byte(maps), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tc.files(mappings) {
+				if err := afero.WriteFile(fs, path.Join("/proc/1", name), body, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s := newSymbolizer(t, afero.NewBasePathFs(fs, "/proc/1"))
+			defer s.Close()
+
+			st := s.Symbolize([]uint64{base + sym.Value + 4, 0x10})
+			if len(st.Frames) != 2 {
+				t.Fatalf("Symbolize() returned %d frames, want 2", len(st.Frames))
+			}
+			frame := st.Frames[0]
+			if !aliases[frame.Func] {
+				t.Errorf("Func = %q, want one of %v", frame.Func, aliases)
+			}
+			if frame.Offset != 4 {
+				t.Errorf("Offset = %d, want 4", frame.Offset)
+			}
+			if frame.Module != library {
+				t.Errorf("Module = %q, want %q", frame.Module, library)
+			}
+			if unmapped := st.Frames[1]; unmapped.Addr != 0x10 || unmapped.Func != "" || unmapped.Module != "" {
+				t.Errorf("unmapped frame = %v, want address only", unmapped)
+			}
+		})
+	}
+}
+
+//go:noinline
+func testTarget() int {
+	return 42
+}
+
+func TestSymbolizeGoBinary(t *testing.T) {
+	// The test binary is a Go binary, possibly stripped and PIE
+	s := newSymbolizer(t, afero.NewBasePathFs(afero.NewOsFs(), "/proc/self"))
+	defer s.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := uint64(reflect.ValueOf(testTarget).Pointer())
+	st := s.Symbolize([]uint64{pc})
+	if len(st.Frames) != 1 {
+		t.Fatalf("Symbolize() returned %d frames, want 1", len(st.Frames))
+	}
+	frame := st.Frames[0]
+	if want := "github.com/noxiouz/gcoredumper/symbolizer.testTarget"; frame.Func != want {
+		t.Errorf("Func = %q, want %q", frame.Func, want)
+	}
+	if frame.Module != exe {
+		t.Errorf("Module = %q, want %q", frame.Module, exe)
+	}
+	if frame.Offset != 0 {
+		t.Errorf("Offset = %d, want 0", frame.Offset)
+	}
+	if !strings.HasSuffix(frame.File, "symbolizer_test.go") || frame.Line == 0 {
+		t.Errorf("File:Line = %s:%d, want symbolizer_test.go", frame.File, frame.Line)
+	}
+}
+
+func TestSymbolizeUnopenableModule(t *testing.T) {
+	procFs := afero.NewMemMapFs()
+	maps := "7f0000000000-7f0000001000 r-xs 00000000 00:01 1234                       /memfd:jit (deleted)\n"
+	if err := afero.WriteFile(procFs, "maps", []byte(maps), 0444); err != nil {
+		t.Fatal(err)
+	}
+	s := newSymbolizer(t, procFs)
+	// the failure is cached, a repeated lookup must not reopen or panic
+	for i := 0; i < 2; i++ {
+		st := s.Symbolize([]uint64{0x7f0000000010})
+		if len(st.Frames) != 1 {
+			t.Fatalf("Symbolize() returned %d frames, want 1", len(st.Frames))
+		}
+		if frame := st.Frames[0]; frame.Module != "/memfd:jit" || frame.Func != "" {
+			t.Errorf("frame = %v, want an unresolved frame of /memfd:jit", frame)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package symbolizer
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"sort"
+)
+
+// functionSymbols returns function symbols of both .symtab and .dynsym
+// sorted by address.
+func functionSymbols(ef *elf.File) []elf.Symbol {
+	var symbols []elf.Symbol
+	for _, read := range []func() ([]elf.Symbol, error){ef.Symbols, ef.DynamicSymbols} {
+		syms, err := read()
+		if err != nil {
+			continue
+		}
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
+				symbols = append(symbols, sym)
+			}
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].Value < symbols[j].Value
+	})
+	return symbols
+}
+
+// findSymbol returns a symbol containing addr. Symbols of unknown size
+// are assumed to span up to the next symbol.
+func findSymbol(symbols []elf.Symbol, addr uint64) (elf.Symbol, bool) {
+	i := sort.Search(len(symbols), func(i int) bool {
+		return symbols[i].Value > addr
+	})
+	if i == 0 {
+		return elf.Symbol{}, false
+	}
+	sym := symbols[i-1]
+	if sym.Size != 0 && addr >= sym.Value+sym.Size {
+		return elf.Symbol{}, false
+	}
+	return sym, true
+}
+
+// goTable returns Go symbol table built from .gopclntab
+// or nil if ef is not a Go binary.
+func goTable(ef *elf.File) *gosym.Table {
+	pclntab := ef.Section(".gopclntab")
+	text := ef.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil
+	}
+	pclnData, err := pclntab.Data()
+	if err != nil {
+		return nil
+	}
+	// .gosymtab is empty since Go 1.3, but older binaries still use it
+	var symtabData []byte
+	if symtab := ef.Section(".gosymtab"); symtab != nil {
+		symtabData, _ = symtab.Data()
+	}
+	table, err := gosym.NewTable(symtabData, gosym.NewLineTable(pclnData, text.Addr))
+	if err != nil {
+		return nil
+	}
+	return table
+}
@@ -1,54 +1,127 @@
 package buildid
 
 import (
+	"bytes"
 	"debug/elf"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 )
 
 // ErrNoBuildId is returned when Build Id has not been detected by the library.
 var ErrNoBuildId = errors.New("No BuildID detected")
 
-type buildId []byte
+const (
+	// NT_GNU_BUILD_ID in a note named "GNU"
+	gnuBuildIDType = 3
+	gnuNoteName    = "GNU"
+	// NT_GO_BUILD_ID in a note named "Go", see cmd/internal/buildid
+	goBuildIDType = 4
+	goNoteName    = "Go"
+)
+
+// note is a single entry of SHT_NOTE section or PT_NOTE segment
+type note struct {
+	Name string
+	Type uint32
+	Desc []byte
+}
 
+// New returns the first build ID note of f: a GNU build ID hex encoded or
+// a Go build ID as is. Notes are looked up in SHT_NOTE sections in the file
+// order and, if none of them holds a build ID, e.g. for files stripped of
+// section headers, in PT_NOTE segments. Malformed sections and segments
+// are skipped.
 func New(f *elf.File) (string, error) {
-	var bid buildId
+	var notes []note
 	for _, section := range f.Sections {
-		switch section.Name {
-		case ".note.gnu.build-id":
-			if err := bid.UnmarshalBinary(section.Open()); err != nil {
-				return "", err
-			}
-			return hex.EncodeToString(bid), nil
-		case ".note.go.buildid": // Go binary
-			if err := bid.UnmarshalBinary(section.Open()); err != nil {
-				return "", err
-			}
-			return string(bid), nil
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+		data, err := section.Data()
+		if err != nil {
+			continue
+		}
+		if n, err := parseNotes(data, f.ByteOrder, section.Addralign); err == nil {
+			notes = append(notes, n...)
 		}
 	}
-	return "", ErrNoBuildId
-}
+	if id, err := fromNotes(notes); err != ErrNoBuildId {
+		return id, err
+	}
 
-func (b *buildId) UnmarshalBinary(r io.Reader) error {
-	var buildIDHeader struct {
-		NameSz uint32
-		DescSz uint32
-		Type   uint32
+	notes = notes[:0]
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		// the reader is limited to Filesz
+		data, err := io.ReadAll(prog.Open())
+		if err != nil {
+			continue
+		}
+		if n, err := parseNotes(data, f.ByteOrder, prog.Align); err == nil {
+			notes = append(notes, n...)
+		}
 	}
-	if err := binary.Read(r, binary.LittleEndian, &buildIDHeader); err != nil {
-		return err
+	return fromNotes(notes)
+}
+
+// fromNotes returns the first build ID in notes. Binaries with both,
+// e.g. cgo ones, are identified by whichever comes first in the file.
+func fromNotes(notes []note) (string, error) {
+	for _, n := range notes {
+		switch {
+		case n.Name == gnuNoteName && n.Type == gnuBuildIDType:
+			return hex.EncodeToString(n.Desc), nil
+		case n.Name == goNoteName && n.Type == goBuildIDType:
+			return string(n.Desc), nil
+		}
 	}
-	name := make([]byte, buildIDHeader.NameSz)
-	if err := binary.Read(r, binary.LittleEndian, name); err != nil {
-		return err
+	return "", ErrNoBuildId
+}
+
+// noteHeaderSize is the size of namesz, descsz and type
+const noteHeaderSize = 12
+
+// parseNotes parses all notes of a section or segment. Name and
+// descriptor end at offsets from the start of the note rounded up to 4
+// bytes, or to 8 bytes if the section or segment is 8 byte aligned.
+func parseNotes(data []byte, order binary.ByteOrder, align uint64) ([]note, error) {
+	if align != 8 {
+		align = 4
 	}
-	id := make([]byte, buildIDHeader.DescSz)
-	if err := binary.Read(r, binary.LittleEndian, id); err != nil {
-		return err
+	var notes []note
+	for len(data) > 0 {
+		if len(data) < noteHeaderSize {
+			return nil, fmt.Errorf("truncated note header: %d bytes", len(data))
+		}
+		nameSz := uint64(order.Uint32(data[0:4]))
+		descSz := uint64(order.Uint32(data[4:8]))
+		typ := order.Uint32(data[8:12])
+		nameEnd := noteHeaderSize + nameSz
+		descStart := alignUp(nameEnd, align)
+		descEnd := descStart + descSz
+		if descEnd > uint64(len(data)) {
+			return nil, fmt.Errorf("truncated note: %d bytes, expected %d", len(data), descEnd)
+		}
+		notes = append(notes, note{
+			Name: string(bytes.TrimRight(data[noteHeaderSize:nameEnd], "\x00")),
+			Type: typ,
+			Desc: data[descStart:descEnd],
+		})
+		// the last note may lack the trailing padding
+		next := alignUp(descEnd, align)
+		if next > uint64(len(data)) {
+			next = uint64(len(data))
+		}
+		data = data[next:]
 	}
-	*b = buildId(id)
-	return nil
+	return notes, nil
+}
+
+func alignUp(n uint64, align uint64) uint64 {
+	return (n + align - 1) &^ (align - 1)
 }
@@ -0,0 +1,363 @@
+package buildid
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// encodeNote serializes a note, name and desc end at offsets
+// from the start of the note padded to align.
+func encodeNote(order binary.ByteOrder, align int, name string, typ uint32, desc []byte) []byte {
+	buf := new(bytes.Buffer)
+	pad := func() {
+		for buf.Len()%align != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	nameBytes := append([]byte(name), 0)
+	binary.Write(buf, order, []uint32{uint32(len(nameBytes)), uint32(len(desc)), typ})
+	buf.Write(nameBytes)
+	pad()
+	buf.Write(desc)
+	pad()
+	return buf.Bytes()
+}
+
+// noteOnlyELF builds an ELF64 file without section headers
+// with a PT_NOTE segment per segments element.
+func noteOnlyELF(order binary.ByteOrder, segments ...[]byte) []byte {
+	return noteELF(order, nil, segments)
+}
+
+// noteELF builds an ELF64 file with a SHT_NOTE section per sections
+// element and a PT_NOTE segment per segments element.
+func noteELF(order binary.ByteOrder, sections [][]byte, segments [][]byte) []byte {
+	data := elf.ELFDATA2LSB
+	if order == binary.BigEndian {
+		data = elf.ELFDATA2MSB
+	}
+	ehsize, phentsize := binary.Size(elf.Header64{}), binary.Size(elf.Prog64{})
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(elf.EM_X86_64),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     uint64(ehsize),
+		Ehsize:    uint16(ehsize),
+		Phentsize: uint16(phentsize),
+		Phnum:     uint16(len(segments)),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(data)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	off := ehsize + phentsize*len(segments)
+	var progs []elf.Prog64
+	for _, notes := range segments {
+		progs = append(progs, elf.Prog64{
+			Type:   uint32(elf.PT_NOTE),
+			Off:    uint64(off),
+			Filesz: uint64(len(notes)),
+			Memsz:  uint64(len(notes)),
+			Align:  4,
+		})
+		off += len(notes)
+	}
+	var shdrs []elf.Section64
+	shstrtab := []byte{0}
+	if len(sections) > 0 {
+		// the null section, the notes and the section name table
+		shdrs = append(shdrs, elf.Section64{})
+		for _, notes := range sections {
+			shdrs = append(shdrs, elf.Section64{
+				Name:      uint32(len(shstrtab)),
+				Type:      uint32(elf.SHT_NOTE),
+				Off:       uint64(off),
+				Size:      uint64(len(notes)),
+				Addralign: 4,
+			})
+			shstrtab = append(shstrtab, ".note\x00"...)
+			off += len(notes)
+		}
+		shdrs = append(shdrs, elf.Section64{
+			Name: uint32(len(shstrtab)),
+			Type: uint32(elf.SHT_STRTAB),
+			Off:  uint64(off),
+			Size: uint64(len(shstrtab) + len(".shstrtab\x00")),
+		})
+		shstrtab = append(shstrtab, ".shstrtab\x00"...)
+		off += len(shstrtab)
+		header.Shoff = uint64(off)
+		header.Shentsize = uint16(binary.Size(elf.Section64{}))
+		header.Shnum = uint16(len(shdrs))
+		header.Shstrndx = uint16(len(shdrs) - 1)
+	}
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, order, &header)
+	binary.Write(buf, order, progs)
+	for _, notes := range segments {
+		buf.Write(notes)
+	}
+	if len(sections) > 0 {
+		for _, notes := range sections {
+			buf.Write(notes)
+		}
+		buf.Write(shstrtab)
+		binary.Write(buf, order, shdrs)
+	}
+	return buf.Bytes()
+}
+
+func TestParseNotes(t *testing.T) {
+	gnuID := []byte{0xde, 0xad, 0xbe, 0xef, 0x01}
+	for _, tc := range []struct {
+		name  string
+		order binary.ByteOrder
+		align int
+	}{
+		{"little endian", binary.LittleEndian, 4},
+		{"big endian", binary.BigEndian, 4},
+		{"8 byte aligned", binary.LittleEndian, 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var raw []byte
+			raw = append(raw, encodeNote(tc.order, tc.align, "stapsdt", 3, []byte{1, 2, 3})...)
+			raw = append(raw, encodeNote(tc.order, tc.align, "Go", goBuildIDType, []byte("abc/def"))...)
+			raw = append(raw, encodeNote(tc.order, tc.align, "GNU", 1, []byte{0, 0, 0, 0})...)
+			raw = append(raw, encodeNote(tc.order, tc.align, "GNU", gnuBuildIDType, gnuID)...)
+			notes, err := parseNotes(raw, tc.order, uint64(tc.align))
+			if err != nil {
+				t.Fatalf("parseNotes failed: %v", err)
+			}
+			want := []note{
+				{Name: "stapsdt", Type: 3, Desc: []byte{1, 2, 3}},
+				{Name: "Go", Type: goBuildIDType, Desc: []byte("abc/def")},
+				{Name: "GNU", Type: 1, Desc: []byte{0, 0, 0, 0}},
+				{Name: "GNU", Type: gnuBuildIDType, Desc: gnuID},
+			}
+			if diff := cmp.Diff(want, notes); diff != "" {
+				t.Errorf("parseNotes() mismatch (-want +got):\n%s", diff)
+			}
+			id, err := fromNotes(notes)
+			if err != nil {
+				t.Fatalf("fromNotes failed: %v", err)
+			}
+			// the Go note comes first
+			if id != "abc/def" {
+				t.Errorf("fromNotes() = %q, want Go build ID", id)
+			}
+		})
+	}
+}
+
+func TestParseNotesTruncated(t *testing.T) {
+	raw := encodeNote(binary.LittleEndian, 4, "GNU", gnuBuildIDType, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+	if _, err := parseNotes(raw[:len(raw)-2], binary.LittleEndian, 4); err == nil {
+		t.Error("truncated note is parsed without an error")
+	}
+}
+
+func TestNewFromProgramHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		order binary.ByteOrder
+		notes [][]byte
+		want  string
+		err   error
+	}{
+		{
+			name:  "GNU little endian",
+			order: binary.LittleEndian,
+			notes: [][]byte{encodeNote(binary.LittleEndian, 4, "GNU", gnuBuildIDType, []byte{0xca, 0xfe})},
+			want:  "cafe",
+		},
+		{
+			name:  "GNU big endian",
+			order: binary.BigEndian,
+			notes: [][]byte{encodeNote(binary.BigEndian, 4, "GNU", gnuBuildIDType, []byte{0xca, 0xfe})},
+			want:  "cafe",
+		},
+		{
+			name:  "Go",
+			order: binary.LittleEndian,
+			notes: [][]byte{encodeNote(binary.LittleEndian, 4, "Go", goBuildIDType, []byte("a/b/c/d"))},
+			want:  "a/b/c/d",
+		},
+		{
+			name:  "no build id",
+			order: binary.LittleEndian,
+			notes: [][]byte{encodeNote(binary.LittleEndian, 4, "GNU", 1, []byte{0, 0, 0, 0})},
+			err:   ErrNoBuildId,
+		},
+		{
+			name:  "malformed segment is skipped",
+			order: binary.LittleEndian,
+			notes: [][]byte{
+				encodeNote(binary.LittleEndian, 4, "GNU", gnuBuildIDType, []byte{0xde, 0xad, 0xbe, 0xef})[:18],
+				encodeNote(binary.LittleEndian, 4, "GNU", gnuBuildIDType, []byte{0xca, 0xfe}),
+			},
+			want: "cafe",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := elf.NewFile(bytes.NewReader(noteOnlyELF(tc.order, tc.notes...)))
+			if err != nil {
+				t.Fatalf("elf.NewFile failed: %v", err)
+			}
+			id, err := New(f)
+			if err != tc.err {
+				t.Fatalf("New() error = %v, want %v", err, tc.err)
+			}
+			if id != tc.want {
+				t.Errorf("New() = %q, want %q", id, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewFromSections(t *testing.T) {
+	le := binary.LittleEndian
+	abiTag := encodeNote(le, 4, "GNU", 1, []byte{0, 0, 0, 0})
+	gnuNote := encodeNote(le, 4, "GNU", gnuBuildIDType, []byte{0xbe, 0xef})
+	goNote := encodeNote(le, 4, "Go", goBuildIDType, []byte("a/b/c/d"))
+	for _, tc := range []struct {
+		name     string
+		sections [][]byte
+		segments [][]byte
+		want     string
+	}{
+		{
+			name:     "sections without build id fall back to segments",
+			sections: [][]byte{abiTag},
+			segments: [][]byte{encodeNote(le, 4, "GNU", gnuBuildIDType, []byte{0xca, 0xfe})},
+			want:     "cafe",
+		},
+		{
+			name:     "sections win over segments",
+			sections: [][]byte{gnuNote},
+			segments: [][]byte{encodeNote(le, 4, "GNU", gnuBuildIDType, []byte{0xca, 0xfe})},
+			want:     "beef",
+		},
+		{
+			// cgo binaries have both, the first section wins
+			name:     "Go note first",
+			sections: [][]byte{abiTag, goNote, gnuNote},
+			want:     "a/b/c/d",
+		},
+		{
+			name:     "GNU note first",
+			sections: [][]byte{gnuNote, goNote},
+			want:     "beef",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := elf.NewFile(bytes.NewReader(noteELF(le, tc.sections, tc.segments)))
+			if err != nil {
+				t.Fatalf("elf.NewFile failed: %v", err)
+			}
+			if len(f.Sections) != len(tc.sections)+2 {
+				t.Fatalf("%d sections, want %d", len(f.Sections), len(tc.sections)+2)
+			}
+			id, err := New(f)
+			if err != nil {
+				t.Fatalf("New() = %v", err)
+			}
+			if id != tc.want {
+				t.Errorf("New() = %q, want %q", id, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewGoBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	id, err := New(f)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if id == "" {
+		t.Error("empty build id")
+	}
+}
+
+func TestParseNotesGNUProperty(t *testing.T) {
+	// .note.gnu.property of x86_64 binaries, 8 byte aligned: name ends at
+	// 16 from the start of the note, so it is not padded at all
+	raw := []byte{
+		0x04, 0x00, 0x00, 0x00, // namesz
+		0x10, 0x00, 0x00, 0x00, // descsz
+		0x05, 0x00, 0x00, 0x00, // NT_GNU_PROPERTY_TYPE_0
+		'G', 'N', 'U', 0x00,
+		0x02, 0x00, 0x00, 0xc0, 0x04, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	notes, err := parseNotes(raw, binary.LittleEndian, 8)
+	if err != nil {
+		t.Fatalf("parseNotes failed: %v", err)
+	}
+	want := []note{{Name: "GNU", Type: 5, Desc: raw[16:]}}
+	if diff := cmp.Diff(want, notes); diff != "" {
+		t.Errorf("parseNotes() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewSystemBinaries(t *testing.T) {
+	var tested int
+	for _, path := range []string{"/bin/ls", "/bin/bash", "/bin/sleep", "/lib64/libc.so.6", "/lib/x86_64-linux-gnu/libc.so.6"} {
+		f, err := elf.Open(path)
+		if err != nil {
+			continue
+		}
+		tested++
+		t.Run(path, func(t *testing.T) {
+			defer f.Close()
+			for _, section := range f.Sections {
+				if section.Type != elf.SHT_NOTE {
+					continue
+				}
+				data, err := section.Data()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := parseNotes(data, f.ByteOrder, section.Addralign); err != nil {
+					t.Errorf("parseNotes(%s) = %v", section.Name, err)
+				}
+			}
+			section := f.Section(".note.gnu.build-id")
+			if section == nil {
+				t.Skip("no .note.gnu.build-id")
+			}
+			data, err := section.Data()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// header and "GNU\x00"
+			want := hex.EncodeToString(data[16:])
+			id, err := New(f)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if id != want {
+				t.Errorf("New() = %q, want %q", id, want)
+			}
+		})
+	}
+	if tested == 0 {
+		t.Skip("no system binaries found")
+	}
+}
@@ -0,0 +1,116 @@
+package procmaps
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mapping is a single line of /proc/<pid>/maps
+type Mapping struct {
+	Start  uint64
+	End    uint64
+	Perms  string
+	Offset uint64
+	Dev    string
+	Inode  uint64
+	// Path is empty for anonymous mappings and
+	// may be a pseudo path like [heap] or [vdso]
+	Path string
+}
+
+// IsExecutable reports whether the mapping has x permission.
+func (m *Mapping) IsExecutable() bool {
+	return len(m.Perms) > 2 && m.Perms[2] == 'x'
+}
+
+// IsFile reports whether the mapping is backed by a file.
+func (m *Mapping) IsFile() bool {
+	return m.Inode != 0 && strings.HasPrefix(m.Path, "/")
+}
+
+// Contains reports whether addr belongs to the mapping.
+func (m *Mapping) Contains(addr uint64) bool {
+	return m.Start <= addr && addr < m.End
+}
+
+// Parse parses the content of /proc/<pid>/maps. Mappings are sorted by Start.
+func Parse(r io.Reader) ([]Mapping, error) {
+	var mappings []Mapping
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		m, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Start < mappings[j].Start
+	})
+	return mappings, nil
+}
+
+// Find returns a mapping containing addr from mappings sorted by Start.
+func Find(mappings []Mapping, addr uint64) (*Mapping, bool) {
+	i := sort.Search(len(mappings), func(i int) bool {
+		return mappings[i].End > addr
+	})
+	if i < len(mappings) && mappings[i].Contains(addr) {
+		return &mappings[i], true
+	}
+	return nil, false
+}
+
+// 7f2c4a1b2000-7f2c4a1d8000 r-xp 00022000 fd:01 1835043    /usr/lib/x86_64-linux-gnu/libc.so.6
+func parseLine(line string) (Mapping, error) {
+	var m Mapping
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return m, fmt.Errorf("malformed maps line %q", line)
+	}
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return m, fmt.Errorf("malformed address range in %q", line)
+	}
+	var err error
+	if m.Start, err = strconv.ParseUint(start, 16, 64); err != nil {
+		return m, fmt.Errorf("malformed start address in %q: %w", line, err)
+	}
+	if m.End, err = strconv.ParseUint(end, 16, 64); err != nil {
+		return m, fmt.Errorf("malformed end address in %q: %w", line, err)
+	}
+	m.Perms = fields[1]
+	if m.Offset, err = strconv.ParseUint(fields[2], 16, 64); err != nil {
+		return m, fmt.Errorf("malformed offset in %q: %w", line, err)
+	}
+	m.Dev = fields[3]
+	if m.Inode, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+		return m, fmt.Errorf("malformed inode in %q: %w", line, err)
+	}
+	// path may contain spaces, so take the rest of the line
+	m.Path = skipFields(line, 5)
+	return m, nil
+}
+
+func skipFields(line string, n int) string {
+	for i := 0; i < n; i++ {
+		line = strings.TrimLeft(line, " \t")
+		if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+			line = line[idx:]
+		} else {
+			return ""
+		}
+	}
+	return strings.TrimLeft(line, " \t")
+}
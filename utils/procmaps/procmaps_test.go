@@ -0,0 +1,52 @@
+package procmaps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const sampleMaps = `55d0c0a00000-55d0c0a02000 r--p 00000000 fd:01 131090                     /usr/bin/cat
+55d0c0a02000-55d0c0a07000 r-xp 00002000 fd:01 131090                     /usr/bin/cat
+55d0c1b3c000-55d0c1b5d000 rw-p 00000000 00:00 0                          [heap]
+7f1e5c600000-7f1e5c628000 r-xp 00028000 fd:01 1835043                    /opt/my app/lib.so (deleted)
+7ffd1a3e0000-7ffd1a3e2000 r-xp 00000000 00:00 0                          [vdso]
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(sampleMaps))
+	if err != nil {
+		t.Fatalf("Parse() returned unexpected error %v", err)
+	}
+	want := []Mapping{
+		{Start: 0x55d0c0a00000, End: 0x55d0c0a02000, Perms: "r--p", Dev: "fd:01", Inode: 131090, Path: "/usr/bin/cat"},
+		{Start: 0x55d0c0a02000, End: 0x55d0c0a07000, Perms: "r-xp", Offset: 0x2000, Dev: "fd:01", Inode: 131090, Path: "/usr/bin/cat"},
+		{Start: 0x55d0c1b3c000, End: 0x55d0c1b5d000, Perms: "rw-p", Dev: "00:00", Path: "[heap]"},
+		{Start: 0x7f1e5c600000, End: 0x7f1e5c628000, Perms: "r-xp", Offset: 0x28000, Dev: "fd:01", Inode: 1835043, Path: "/opt/my app/lib.so (deleted)"},
+		{Start: 0x7ffd1a3e0000, End: 0x7ffd1a3e2000, Perms: "r-xp", Dev: "00:00", Path: "[vdso]"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+	}
+
+	m, ok := Find(got, 0x55d0c0a02010)
+	if !ok || !m.IsExecutable() || !m.IsFile() || m.Offset != 0x2000 {
+		t.Errorf("Find() = %+v, %v, want executable mapping of /usr/bin/cat", m, ok)
+	}
+	if _, ok := Find(got, 0x55d0c0a07000); ok {
+		t.Errorf("Find() found a mapping for an unmapped address")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, line := range []string{
+		"55d0c0a00000 r--p 00000000 fd:01 131090 /usr/bin/cat",
+		"zz-55d0c0a02000 r--p 00000000 fd:01 131090 /usr/bin/cat",
+		"55d0c0a00000-55d0c0a02000 r--p",
+	} {
+		if _, err := Parse(strings.NewReader(line)); err == nil {
+			t.Errorf("Parse(%q) expected to return an error, but got nil", line)
+		}
+	}
+}